@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/kevin-biot/rtgf/rtgf-registry/internal/api"
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/crypto"
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/reload"
 	"github.com/kevin-biot/rtgf/rtgf-registry/internal/verify"
 	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
 )
@@ -14,29 +24,174 @@ import (
 func main() {
 	addr := flag.String("addr", ":8080", "listen address")
 	staticDir := flag.String("static-dir", "../registry/static/tokens", "path to token fixtures")
+	baseURL := flag.String("base-url", "", "public registry base URL (defaults to $RTGF_URL)")
+	issuerDID := flag.String("issuer-did", api.DefaultIssuerDID, "issuer DID advertised in /.well-known/rtgf")
+	policyMaxTTL := flag.Duration("policy-max-ttl", api.DefaultPolicyMaxTTL, "maximum token lifetime advertised to clients")
+	domains := flag.String("domains", "", "comma-separated supported domain codes (defaults to catalog domains)")
+	manifest := flag.String("manifest", "", "token manifest JSON, relative to --static-dir")
+	scan := flag.Bool("scan", false, "derive the token catalog by scanning --static-dir")
+	reloadInterval := flag.Duration("reload-interval", 0, "poll --static-dir for changes at this interval (0 disables; SIGHUP always reloads)")
+	integrity := flag.String("integrity", string(api.IntegrityStrict), "token digest enforcement: strict (refuse to start), quarantine or off")
+	skew := flag.Duration("skew", verify.DefaultSkew, "clock skew tolerated around token nbf/exp (negative disables)")
+	batchWorkers := flag.Int("batch-workers", verify.DefaultBatchWorkers, "concurrent evaluations per /verify/batch request")
+	allowHistorical := flag.Bool("allow-historical", false, "honour the /verify \"at\" parameter (never enable in production)")
+	trustedJWKS := flag.String("trusted-jwks", "", "comma-separated JWKS files of partner registries trusted for inline tokens")
+	replaySize := flag.Int("replay-cache-size", verifylib.DefaultReplayCapacity, "maximum jti values remembered by the replay cache")
+	replayJournal := flag.String("replay-journal", "", "persist the jti replay cache to this JSON-lines file")
+	signingKey := flag.String("signing-key", "", "private Ed25519 JWK used to sign /verify receipts; its kid must be in jwks.json")
+	mandalaPath := flag.String("mandala", "", "Mandala announcement listing proof types accepted as evidence (defaults to .well-known/mandala.json in the parent of --static-dir)")
+	digestHeader := flag.Bool("digest-header", false, "send the verified token digest as "+api.DigestHeader)
 	flag.Parse()
 
+	integrityMode, err := api.ParseIntegrityMode(*integrity)
+	if err != nil {
+		log.Fatalf("parse --integrity: %v", err)
+	}
+
 	fsys := os.DirFS(*staticDir)
+	catalog, err := loadCatalog(fsys, *manifest, *scan)
+	if err != nil {
+		log.Fatalf("load catalog: %v", err)
+	}
 	server, err := api.NewServer(api.Config{
-		StaticFS: fsys,
+		StaticFS:     fsys,
+		Tokens:       api.TokensFromCatalog(catalog),
+		BaseURL:      *baseURL,
+		IssuerDID:    *issuerDID,
+		PolicyMaxTTL: *policyMaxTTL,
+		Domains:      splitList(*domains),
+		Integrity:    integrityMode,
+		DigestHeader: *digestHeader,
 	})
 	if err != nil {
 		log.Fatalf("init server: %v", err)
 	}
 
-	staticVerifier, err := verifylib.NewStaticVerifier(fsys, ".", nil)
+	for _, q := range server.Quarantined() {
+		log.Printf("quarantined token %s: %s", q.URI, q.Reason)
+	}
+	catalog = reload.WithoutQuarantined(catalog, server.Quarantined())
+	staticVerifier, err := verifylib.NewStaticVerifierFromCatalog(fsys, ".", catalog, server.Now())
 	if err != nil {
 		log.Fatalf("init static verifier: %v", err)
 	}
-	verifyService := verify.NewService(1, staticVerifier)
+	clock := verify.SystemClock
+	if fixed := os.Getenv("FIXED_TIME"); fixed != "" {
+		ts, err := time.Parse(time.RFC3339, fixed)
+		if err != nil {
+			log.Fatalf("parse FIXED_TIME: %v", err)
+		}
+		clock = verify.FixedClock(ts)
+	}
+	trusted, err := loadTrustedKeys(splitList(*trustedJWKS))
+	if err != nil {
+		log.Fatalf("load --trusted-jwks: %v", err)
+	}
+	registryKeys := reload.RegistryKeys(fsys)
+	var signer *crypto.Signer
+	if *signingKey != "" {
+		if signer, err = crypto.LoadSigner(*signingKey); err != nil {
+			log.Fatalf("load --signing-key: %v", err)
+		}
+		if published, ok := registryKeys[signer.Kid()]; !ok || !published.Equal(signer.Public()) {
+			log.Fatalf("--signing-key %q is not published in jwks.json", signer.Kid())
+		}
+	}
+	mandalaFS, mandalaFile := os.DirFS(filepath.Dir(filepath.Clean(*staticDir))), reload.MandalaPath
+	if *mandalaPath != "" {
+		mandalaFS, mandalaFile = os.DirFS(filepath.Dir(*mandalaPath)), filepath.Base(*mandalaPath)
+	}
+	mandala, err := reload.LoadMandala(mandalaFS, mandalaFile)
+	if err != nil {
+		log.Fatalf("load mandala announcement: %v", err)
+	}
+	if err := reload.CheckMandala(staticVerifier, catalog, mandala); err != nil {
+		log.Fatalf("check mandala announcement: %v", err)
+	}
+	var replay verifylib.ReplayCache = verifylib.NewMemoryReplayCache(*replaySize)
+	if *replayJournal != "" {
+		journal, err := verifylib.OpenFileReplayCache(*replayJournal, *replaySize, clock.Now())
+		if err != nil {
+			log.Fatalf("open --replay-journal: %v", err)
+		}
+		defer journal.Close()
+		replay = journal
+	}
+	verifyService := verify.NewService(1, staticVerifier, verify.Options{
+		Clock:           clock,
+		Skew:            *skew,
+		AllowHistorical: *allowHistorical,
+		BatchWorkers:    *batchWorkers,
+		RegistryKeys:    registryKeys,
+		TrustedKeys:     trusted,
+		Replay:          replay,
+		Signer:          signer,
+		Issuer:          *issuerDID,
+		Mandala:         mandala,
+	})
+
+	reloader, err := reload.New(fsys, func() (verifylib.Catalog, error) {
+		return loadCatalog(fsys, *manifest, *scan)
+	}, server, verifyService)
+	if err != nil {
+		log.Fatalf("init reloader: %v", err)
+	}
+	reloader.SetMandalaSource(mandalaFS, mandalaFile)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go reloader.Run(context.Background(), *reloadInterval, hup)
+
 	mux := http.NewServeMux()
 	mux.Handle("/", server)
 	mux.HandleFunc("/verify", verifyService.HandleVerify)
+	mux.HandleFunc("/verify/batch", verifyService.HandleVerifyBatch)
+	mux.HandleFunc("/quote", verifyService.HandleQuote)
+	mux.HandleFunc("/settlement", verifyService.HandleSettlement)
 	mux.HandleFunc("/revocations", verifyService.HandleRevocationsGet)
 	mux.HandleFunc("/revocations/bump", verifyService.HandleRevocationsBump)
 
-	log.Printf("rtgf-registryd listening on %s (static dir: %s)", *addr, *staticDir)
+	log.Printf("rtgf-registryd listening on %s (static dir: %s, %d tokens)", *addr, *staticDir, len(catalog))
 	if err := http.ListenAndServe(*addr, mux); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 }
+
+// loadTrustedKeys merges the Ed25519 keys of the given JWKS files.
+func loadTrustedKeys(paths []string) (verifylib.KeySet, error) {
+	var keys verifylib.KeySet
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := verifylib.ParseJWKS(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = keys.Merge(parsed)
+	}
+	return keys, nil
+}
+
+// loadCatalog resolves the shared token catalog from a manifest, a directory
+// scan, or the bundled defaults, in that order of precedence.
+func loadCatalog(fsys fs.FS, manifest string, scan bool) (verifylib.Catalog, error) {
+	switch {
+	case manifest != "":
+		return verifylib.LoadManifest(fsys, manifest, ".")
+	case scan:
+		return verifylib.ScanCatalog(fsys, ".")
+	default:
+		return verifylib.DefaultCatalog, nil
+	}
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -3,27 +3,60 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/fs"
 	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/transparency"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
 )
 
 // Config encapsulates the resources exposed by the registry API.
 type Config struct {
 	StaticFS fs.FS
 	Tokens   map[string]TokenEntry
+	// Now overrides the clock used to evaluate token validity windows.
+	Now func() time.Time
+	// BaseURL is the public registry root; defaults to the RTGF_URL environment variable.
+	BaseURL string
+	// IssuerDID identifies the registry as token issuer and trust anchor.
+	IssuerDID string
+	// PolicyMaxTTL is the maximum token lifetime advertised to clients.
+	PolicyMaxTTL time.Duration
+	// Domains lists supported domain codes; defaults to those present in Tokens.
+	Domains []string
+	// CacheTTL caps the Cache-Control max-age of served resources; defaults to PolicyMaxTTL.
+	CacheTTL time.Duration
+	// Issuers lists catalog issuers; defaults to IssuerDID with the registry JWKS.
+	Issuers []CatalogIssuer
+	// Corridors lists catalog corridors; defaults to the corridors of published IMTs.
+	Corridors []CatalogCorridor
+	// Integrity selects how token digests are checked when the index is built;
+	// defaults to IntegrityOff.
+	Integrity IntegrityMode
+	// DigestHeader adds the verified canonical digest of served tokens as
+	// X-RTGF-Token-Digest. It has no effect when Integrity is off.
+	DigestHeader bool
 }
 
+const (
+	// DefaultIssuerDID is advertised when Config.IssuerDID is empty.
+	DefaultIssuerDID = "did:org:rtgf.eu"
+	// DefaultPolicyMaxTTL follows the draft's recommended 24 hour ceiling.
+	DefaultPolicyMaxTTL = 24 * time.Hour
+)
+
 // Server serves the registry HTTP interface backed by static fixtures.
 type Server struct {
-	cfg       Config
-	mux       *http.ServeMux
-	tokens    map[string]TokenEntry
-	slugIndex map[string]TokenEntry
-	jwks      []byte
-	jwksURL   string
+	cfg     Config
+	mux     *http.ServeMux
+	index   atomic.Pointer[index]
+	jwksURL string
+	baseURL string
 }
 
 // TokenEntry describes a published token and associated transparency metadata.
@@ -38,70 +71,54 @@ type TokenEntry struct {
 	NotBefore string `json:"nbf"`
 	ExpiresAt string `json:"exp"`
 	Revoked   bool   `json:"revoked"`
+
+	Issuer        string   `json:"iss,omitempty"`
+	Jurisdictions []string `json:"jurisdictions,omitempty"`
+	Corridor      string   `json:"corridor,omitempty"`
+	Domains       []string `json:"domains,omitempty"`
+	// RequiredTokens lists token types an IMT's corridor mandates beyond the default tuple.
+	RequiredTokens []string `json:"required_tokens,omitempty"`
+}
+
+// info returns the version metadata verifylib orders and selects by.
+func (e TokenEntry) info() verifylib.TokenInfo {
+	return verifylib.TokenInfo{
+		URI: e.URI, Type: e.Type, Version: e.Version, IssuedAt: e.IssuedAt,
+		NotBefore: e.NotBefore, ExpiresAt: e.ExpiresAt, Revoked: e.Revoked, Hash: e.Hash,
+	}
+}
+
+// Now returns the server clock that selects current token versions.
+func (s *Server) Now() time.Time {
+	return s.cfg.Now()
 }
 
 // DefaultTokens enumerates the static fixture metadata served by the registry.
-var DefaultTokens = map[string]TokenEntry{
-	"urn:lane2:token:RRMT:EU:PSD3:3.2": {
-		URI:       "urn:lane2:token:RRMT:EU:PSD3:3.2",
-		Type:      "RRMT",
-		Slug:      "eu-psd3-2025",
-		Filename:  "rrmt-eu-psd3-2025.json",
-		Hash:      "sha256:69da10672d528c5c245d911424159dfa9703843b28e8810a5f42d3386251ed27",
-		Version:   "2025.10",
-		IssuedAt:  "2025-10-01T00:00:00Z",
-		NotBefore: "2025-10-01T00:00:00Z",
-		ExpiresAt: "2026-10-01T00:00:00Z",
-		Revoked:   false,
-	},
-	"urn:lane2:token:CORT:VODAFONE.VISA:2025": {
-		URI:       "urn:lane2:token:CORT:VODAFONE.VISA:2025",
-		Type:      "CORT",
-		Slug:      "vodafone-visa-2025",
-		Filename:  "cort-vodafone-visa-2025.json",
-		Hash:      "sha256:e90a7d9cb49299a8a2809f6e6f8268810c3036a5a50bdb82c03b4d94358196da",
-		Version:   "2025-Q4",
-		IssuedAt:  "2025-10-01T00:00:00Z",
-		NotBefore: "2025-10-01T00:00:00Z",
-		ExpiresAt: "2026-04-01T00:00:00Z",
-		Revoked:   false,
-	},
-	"urn:lane2:token:PSRT:VISA:ACQ-123": {
-		URI:       "urn:lane2:token:PSRT:VISA:ACQ-123",
-		Type:      "PSRT",
-		Slug:      "visa-acq-123",
-		Filename:  "psrt-visa-acq-123.json",
-		Hash:      "sha256:395f95cce27efd422446b973fa06cb2cccc7eeed828ed697daf402859747657b",
-		Version:   "2025-01",
-		IssuedAt:  "2025-10-01T00:00:00Z",
-		NotBefore: "2025-10-01T00:00:00Z",
-		ExpiresAt: "2026-01-01T00:00:00Z",
-		Revoked:   false,
-	},
-	"urn:lane2:token:RMT:EU:PSD3:3.2": {
-		URI:       "urn:lane2:token:RMT:EU:PSD3:3.2",
-		Type:      "RMT",
-		Slug:      "eu-psd3-2025",
-		Filename:  "rrmt-eu-psd3-2025.json",
-		Hash:      "sha256:69da10672d528c5c245d911424159dfa9703843b28e8810a5f42d3386251ed27",
-		Version:   "2025.10",
-		IssuedAt:  "2025-10-01T00:00:00Z",
-		NotBefore: "2025-10-01T00:00:00Z",
-		ExpiresAt: "2026-10-01T00:00:00Z",
-		Revoked:   false,
-	},
-	"urn:lane2:token:IMT:EU:SG:2025": {
-		URI:       "urn:lane2:token:IMT:EU:SG:2025",
-		Type:      "IMT",
-		Slug:      "eu-sg-2025",
-		Filename:  "imt-eu-sg-2025.json",
-		Hash:      "sha256:86273b5161f566be632b7cf629f8c37c46a125fddfe8c2ad0ca8f583182d5fa1",
-		Version:   "2025.10",
-		IssuedAt:  "2025-10-01T00:00:00Z",
-		NotBefore: "2025-10-01T00:00:00Z",
-		ExpiresAt: "2026-10-01T00:00:00Z",
-		Revoked:   false,
-	},
+var DefaultTokens = TokensFromCatalog(verifylib.DefaultCatalog)
+
+// TokensFromCatalog indexes a shared verify-lib catalog by token URI. Further
+// versions of an already indexed URI are keyed as "<uri>@<version>".
+func TokensFromCatalog(catalog verifylib.Catalog) map[string]TokenEntry {
+	tokens := make(map[string]TokenEntry, len(catalog))
+	for _, entry := range catalog {
+		key := entry.URI
+		if _, taken := tokens[key]; taken {
+			key = describeVersion(entry.URI, entry.Version)
+		}
+		tokens[key] = TokenEntry{
+			URI:       entry.URI,
+			Type:      entry.Type,
+			Slug:      entry.Slug,
+			Filename:  entry.File,
+			Hash:      entry.Hash,
+			Version:   entry.Version,
+			IssuedAt:  entry.IssuedAt,
+			NotBefore: entry.NotBefore,
+			ExpiresAt: entry.ExpiresAt,
+			Revoked:   entry.Revoked,
+		}
+	}
+	return tokens
 }
 
 // NewServer validates configuration and returns a ready-to-serve API instance.
@@ -114,34 +131,45 @@ func NewServer(cfg Config) (*Server, error) {
 		tokenCatalog = DefaultTokens
 	}
 
-	tokens := make(map[string]TokenEntry, len(tokenCatalog))
-	slugIndex := make(map[string]TokenEntry, len(tokenCatalog))
-	for uri, entry := range tokenCatalog {
-		tokens[uri] = entry
-		if entry.Type != "" && entry.Slug != "" {
-			key := strings.ToLower(entry.Type) + ":" + entry.Slug
-			slugIndex[key] = entry
-		}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("RTGF_URL")
 	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	jwksURL := baseURL + "/jwks.json"
 
-	jwksData, err := fs.ReadFile(cfg.StaticFS, "jwks.json")
-	if err != nil {
-		return nil, fmt.Errorf("load jwks: %w", err)
+	cfg.Tokens = tokenCatalog
+	cfg.BaseURL = baseURL
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.IssuerDID == "" {
+		cfg.IssuerDID = DefaultIssuerDID
+	}
+	if cfg.PolicyMaxTTL <= 0 {
+		cfg.PolicyMaxTTL = DefaultPolicyMaxTTL
+	}
+	if cfg.CacheTTL <= 0 || cfg.CacheTTL > cfg.PolicyMaxTTL {
+		cfg.CacheTTL = cfg.PolicyMaxTTL
+	}
+	if cfg.Integrity == "" {
+		cfg.Integrity = IntegrityOff
 	}
-	baseURL := strings.TrimSuffix(os.Getenv("RTGF_URL"), "/")
-	jwksURL := "/jwks.json"
-	if baseURL != "" {
-		jwksURL = baseURL + jwksURL
+	if len(cfg.Issuers) == 0 {
+		cfg.Issuers = []CatalogIssuer{{Iss: cfg.IssuerDID, JWKS: jwksURL}}
 	}
 
 	s := &Server{
-		cfg:       Config{StaticFS: cfg.StaticFS, Tokens: tokenCatalog},
-		mux:       http.NewServeMux(),
-		tokens:    tokens,
-		slugIndex: slugIndex,
-		jwks:      jwksData,
-		jwksURL:   jwksURL,
+		cfg:     cfg,
+		mux:     http.NewServeMux(),
+		jwksURL: jwksURL,
+		baseURL: baseURL,
 	}
+	idx, err := s.buildIndex(tokenCatalog)
+	if err != nil {
+		return nil, err
+	}
+	s.index.Store(idx)
 	s.routes()
 	return s, nil
 }
@@ -152,127 +180,129 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) routes() {
+	s.mux.HandleFunc("/", s.handleNotFound)
 	s.mux.HandleFunc("/healthz", s.handleHealth)
 	s.mux.HandleFunc("/tokens", s.handleTokenByURI)
 	s.mux.Handle("/tokens/", http.HandlerFunc(s.handleTokenByType))
+	s.mux.Handle("/rmt/", http.HandlerFunc(s.handleRMT))
+	s.mux.Handle("/imt/", http.HandlerFunc(s.handleIMT))
+	s.mux.HandleFunc("/search", s.handleSearch)
 	s.mux.HandleFunc("/catalog", s.handleCatalog)
+	s.mux.HandleFunc("/.well-known/rtgf", s.handleDiscovery)
 	s.mux.HandleFunc("/.well-known/rtgf/catalog.json", s.handleCatalog)
 	s.mux.HandleFunc("/jwks.json", s.handleJWKS)
+	s.mux.Handle("/transparency", transparency.Handler())
+}
+
+func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, problem.NotFound, "")
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		problem.Write(w, r, problem.MethodNotAllowed, "")
 		return
 	}
+	health := struct {
+		Status      string             `json:"status"`
+		Quarantined []QuarantinedToken `json:"quarantined,omitempty"`
+	}{Status: "ok", Quarantined: s.current().quarantined}
+	if len(health.Quarantined) > 0 {
+		health.Status = "degraded"
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	_ = json.NewEncoder(w).Encode(health)
 }
 
 func (s *Server) handleTokenByURI(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		problem.Write(w, r, problem.MethodNotAllowed, "")
 		return
 	}
 	uri := strings.TrimSpace(r.URL.Query().Get("uri"))
 	if uri == "" {
-		http.Error(w, "missing uri query parameter", http.StatusBadRequest)
+		problem.Write(w, r, problem.InvalidRequest, "missing uri query parameter")
 		return
 	}
-	entry, ok := s.tokens[uri]
+	version := strings.TrimSpace(r.URL.Query().Get("version"))
+	idx := s.current()
+	entry, ok := idx.lookupURI(uri, version, s.cfg.Now())
 	if !ok {
-		http.NotFound(w, r)
+		problem.Write(w, r, problem.TokenNotFound, "no token published for "+describeVersion(uri, version))
 		return
 	}
-	s.serveStaticJSON(w, r, entry)
+	s.serveStaticJSON(w, r, idx, entry)
 }
 
 func (s *Server) handleTokenByType(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		problem.Write(w, r, problem.MethodNotAllowed, "")
 		return
 	}
 	trimmed := strings.TrimPrefix(r.URL.Path, "/tokens/")
 	parts := strings.SplitN(trimmed, "/", 2)
 	if len(parts) != 2 {
-		http.NotFound(w, r)
+		problem.Write(w, r, problem.NotFound, "")
 		return
 	}
 	tokenType := strings.ToLower(parts[0])
-	slug := parts[1]
+	slug, listVersions := strings.CutSuffix(parts[1], "/versions")
+	var version string
+	if at := strings.LastIndex(slug, "@"); at >= 0 && !listVersions {
+		slug, version = slug[:at], slug[at+1:]
+		if version == "" {
+			problem.Write(w, r, problem.InvalidRequest, "empty token version")
+			return
+		}
+	}
 	if slug == "" || strings.Contains(slug, "..") {
-		http.Error(w, "invalid token identifier", http.StatusBadRequest)
+		problem.Write(w, r, problem.InvalidRequest, "invalid token identifier")
+		return
+	}
+	idx := s.current()
+	if listVersions {
+		s.serveVersions(w, r, idx, tokenType, slug)
 		return
 	}
-	entry, err := s.lookupBySlug(tokenType, slug)
+	entry, err := idx.lookupBySlug(tokenType, slug, version, s.cfg.Now())
 	if err != nil {
 		if errors.Is(err, errNotFound) {
-			http.NotFound(w, r)
+			problem.Write(w, r, problem.TokenNotFound, "no "+tokenType+" token with slug "+describeVersion(slug, version))
 			return
 		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Write(w, r, problem.InvalidRequest, err.Error())
 		return
 	}
-	s.serveStaticJSON(w, r, entry)
+	s.serveStaticJSON(w, r, idx, entry)
 }
 
-func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		problem.Write(w, r, problem.MethodNotAllowed, "")
 		return
 	}
-	type issuer struct {
-		Iss  string `json:"iss"`
-		JWKS string `json:"jwks"`
-	}
-	type corridor struct {
-		ID             string   `json:"id"`
-		RequiredTokens []string `json:"requiredTokens"`
-	}
-	type catalog struct {
-		RegistrySnapshotID string     `json:"registrySnapshotId"`
-		Issuers            []issuer   `json:"issuers"`
-		Corridors          []corridor `json:"corridors"`
-	}
-	payload := catalog{
-		RegistrySnapshotID: "sha256:rtgf-catalog-2025-10-01",
-		Issuers: []issuer{
-			{Iss: "did:org:rtgf.eu", JWKS: s.jwksURL},
-		},
-		Corridors: []corridor{
-			{ID: "EU:THA:CRAFT-01", RequiredTokens: []string{"RMT", "IMT", "CORT", "PSRT"}},
-		},
-	}
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	jwks := s.current().jwks
+	serveCacheable(w, r, jwks, contentETag(jwks), s.cfg.CacheTTL, "application/json")
 }
 
-func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(s.jwks)
+func (s *Server) serveStaticJSON(w http.ResponseWriter, r *http.Request, idx *index, entry TokenEntry) {
+	s.serveToken(w, r, idx, entry, "application/json")
 }
-func (s *Server) serveStaticJSON(w http.ResponseWriter, r *http.Request, entry TokenEntry) {
-	data, err := fs.ReadFile(s.cfg.StaticFS, entry.Filename)
-	if err != nil {
-		http.NotFound(w, r)
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request, idx *index, entry TokenEntry, contentType string) {
+	data, ok := idx.payloads[entry.Filename]
+	if !ok {
+		problem.Write(w, r, problem.TokenNotFound, "token payload unavailable for "+entry.URI)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(data)
+	if digest := idx.digests[entry.Filename]; s.cfg.DigestHeader && digest != "" {
+		w.Header().Set(DigestHeader, digest)
+	}
+	etag := entryETag(entry)
+	if etag == "" {
+		etag = contentETag(data)
+	}
+	serveCacheable(w, r, data, etag, s.tokenMaxAge(entry), contentType)
 }
 
 var errNotFound = errors.New("not found")
-
-func (s *Server) lookupBySlug(tokenType, slug string) (TokenEntry, error) {
-	key := tokenType + ":" + slug
-	if entry, ok := s.slugIndex[key]; ok {
-		return entry, nil
-	}
-	return TokenEntry{}, errNotFound
-}
@@ -2,11 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"testing/fstest"
+	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/transparency"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
 )
 
 func TestHealthz(t *testing.T) {
@@ -64,6 +69,34 @@ func TestTokenLookupNotFound(t *testing.T) {
 	if rec.Code != http.StatusNotFound {
 		t.Fatalf("expected 404, got %d", rec.Code)
 	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	var body struct {
+		Type   string `json:"type"`
+		Status int    `json:"status"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal problem: %v", err)
+	}
+	if body.Type != "https://lane2.ai/ietf/imt-rmt/errors#token_not_found" || body.Status != http.StatusNotFound {
+		t.Fatalf("unexpected problem %+v", body)
+	}
+}
+
+func TestUnknownPathProblem(t *testing.T) {
+	s := newTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
 }
 
 func TestTokenLookupMethodNotAllowed(t *testing.T) {
@@ -100,28 +133,92 @@ func TestCatalogEndpoint(t *testing.T) {
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rec.Code)
 	}
-	var payload struct {
-		RegistrySnapshotID string `json:"registrySnapshotId"`
-		Issuers            []struct {
-			Iss  string `json:"iss"`
-			JWKS string `json:"jwks"`
-		} `json:"issuers"`
-		Corridors []struct {
-			ID             string   `json:"id"`
-			RequiredTokens []string `json:"requiredTokens"`
-		} `json:"corridors"`
-	}
+	var payload Catalog
 	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
 		t.Fatalf("unmarshal catalog: %v", err)
 	}
-	if payload.RegistrySnapshotID == "" {
-		t.Fatalf("expected registry snapshot id")
+	if !strings.HasPrefix(payload.RegistrySnapshotID, "sha256:") || len(payload.RegistrySnapshotID) != len("sha256:")+64 {
+		t.Fatalf("expected sha256 registry snapshot id, got %q", payload.RegistrySnapshotID)
 	}
-	if len(payload.Issuers) == 0 || payload.Issuers[0].JWKS == "" {
+	if len(payload.Issuers) == 0 || payload.Issuers[0].JWKS == "" || payload.Issuers[0].Iss != DefaultIssuerDID {
 		t.Fatalf("expected issuer jwks")
 	}
-	if len(payload.Corridors) == 0 || payload.Corridors[0].ID != "EU:THA:CRAFT-01" {
-		t.Fatalf("unexpected corridors: %+v", payload.Corridors)
+	if payload.Corridors == nil || len(payload.Corridors) != 0 {
+		t.Fatalf("expected empty corridor list, got %+v", payload.Corridors)
+	}
+	if len(payload.Tokens) != 2 || payload.Tokens[0].URI != "urn:lane2:token:CORT:TEST" {
+		t.Fatalf("expected sorted token entries, got %+v", payload.Tokens)
+	}
+	if got := payload.Tokens[1]; got.Slug != "rrmt-slug" || got.Hash != "sha256:test" || got.NotBefore == "" || got.ExpiresAt == "" {
+		t.Fatalf("unexpected token entry %+v", got)
+	}
+}
+
+func TestCatalogFromConfigAndIndex(t *testing.T) {
+	fsys := fstest.MapFS{
+		"jwks.json": {Data: []byte(`{"keys":[]}`)},
+		"imt.json":  {Data: []byte(`{"type":"IMT","corridor":"EU->SG","domains":["payments_psd3"]}`)},
+	}
+	tokens := map[string]TokenEntry{
+		"urn:test:imt": {URI: "urn:test:imt", Type: "IMT", Filename: "imt.json", Hash: "sha256:imt"},
+	}
+	derived, err := NewServer(Config{StaticFS: fsys, Tokens: tokens})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	configured, err := NewServer(Config{
+		StaticFS:  fsys,
+		Tokens:    tokens,
+		Issuers:   []CatalogIssuer{{Iss: "did:web:a", JWKS: "https://a/jwks.json"}, {Iss: "did:web:b", JWKS: "https://b/jwks.json"}},
+		Corridors: []CatalogCorridor{{ID: "EU-SG", RequiredTokens: []string{"RMT", "IMT"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	fetch := func(s *Server) Catalog {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+		var c Catalog
+		if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil {
+			t.Fatalf("unmarshal catalog: %v", err)
+		}
+		return c
+	}
+	a, b := fetch(derived), fetch(configured)
+	if len(a.Corridors) != 1 || a.Corridors[0].ID != "EU-SG" || len(a.Corridors[0].RequiredTokens) != 4 {
+		t.Fatalf("expected corridor derived from IMT, got %+v", a.Corridors)
+	}
+	if len(b.Issuers) != 2 || len(b.Corridors[0].RequiredTokens) != 2 {
+		t.Fatalf("expected configured issuers/corridors, got %+v", b)
+	}
+	if a.RegistrySnapshotID == b.RegistrySnapshotID {
+		t.Fatalf("expected snapshot id to reflect catalog content")
+	}
+	if again := fetch(derived); again.RegistrySnapshotID != a.RegistrySnapshotID {
+		t.Fatalf("expected deterministic snapshot id")
+	}
+}
+
+func TestCatalogCorridorRequiresAMLTokens(t *testing.T) {
+	fsys := fstest.MapFS{
+		"jwks.json": {Data: []byte(`{"keys":[]}`)},
+		"imt.json":  {Data: []byte(`{"type":"IMT","corridor":"EU-US","required_tokens":["IMT","amls","AMLV"]}`)},
+	}
+	s, err := NewServer(Config{StaticFS: fsys, Tokens: map[string]TokenEntry{
+		"urn:test:imt": {Type: "IMT", Filename: "imt.json", Hash: "sha256:imt"},
+	}})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+	var c Catalog
+	if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil {
+		t.Fatalf("unmarshal catalog: %v", err)
+	}
+	if len(c.Corridors) != 1 || strings.Join(c.Corridors[0].RequiredTokens, ",") != "RMT,IMT,CORT,PSRT,AMLS,AMLV" {
+		t.Fatalf("expected AML tokens appended to the default tuple, got %+v", c.Corridors)
 	}
 }
 
@@ -239,3 +336,510 @@ func newTestServer(t *testing.T) *Server {
 	}
 	return server
 }
+
+func TestRMTLookupSelectsLatestValid(t *testing.T) {
+	s := newResolveTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/rmt/eu/payments_psd3", nil)
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/imt-rmt+json" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `"version":"v2"`) {
+		t.Fatalf("expected v2 token, got %s", rec.Body.String())
+	}
+}
+
+func TestRMTLookupNotFound(t *testing.T) {
+	s := newResolveTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/rmt/SG/payments_psd3", nil)
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestRMTLookupInvalidJurisdiction(t *testing.T) {
+	s := newResolveTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/rmt/EUR/payments_psd3", nil)
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestIMTLookupByCorridor(t *testing.T) {
+	s := newResolveTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/imt/EU-SG/payments_psd3", nil)
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"type":"IMT"`) {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func TestIMTLookupInvalidCorridor(t *testing.T) {
+	s := newResolveTestServer(t)
+	for _, corridor := range []string{"EU", "EU-SG-MY", "EU_SG", "E1-SG"} {
+		req := httptest.NewRequest(http.MethodGet, "/imt/"+corridor+"/payments_psd3", nil)
+		rec := httptest.NewRecorder()
+
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("corridor %q: expected 400, got %d", corridor, rec.Code)
+		}
+	}
+}
+
+func newResolveTestServer(t *testing.T) *Server {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"rmt-v1.json":      {Data: []byte(`{"type":"RMT","version":"v1","jurisdiction":["EU"],"domain":"payments_psd3"}`)},
+		"rmt-v2.json":      {Data: []byte(`{"type":"RMT","version":"v2","jurisdiction":["EU"],"domain":"payments_psd3"}`)},
+		"rmt-v3.json":      {Data: []byte(`{"type":"RMT","version":"v3","jurisdiction":["EU"],"domain":"payments_psd3"}`)},
+		"rmt-revoked.json": {Data: []byte(`{"type":"RMT","version":"v4","jurisdiction":"EU","domain":"payments_psd3"}`)},
+		"imt.json":         {Data: []byte(`{"type":"IMT","corridor":"EU->SG","domains":["payments_psd3"]}`)},
+		"jwks.json":        {Data: []byte(`{"keys":[]}`)},
+	}
+	entry := func(uri, file, version, nbf, exp string, revoked bool) TokenEntry {
+		return TokenEntry{URI: uri, Type: strings.Split(uri, ":")[3], Filename: file, Version: version, NotBefore: nbf, ExpiresAt: exp, Revoked: revoked}
+	}
+	entries := map[string]TokenEntry{
+		"urn:lane2:token:RMT:EU:PSD3:1": entry("urn:lane2:token:RMT:EU:PSD3:1", "rmt-v1.json", "v1", "2025-01-01T00:00:00Z", "2027-01-01T00:00:00Z", false),
+		"urn:lane2:token:RMT:EU:PSD3:2": entry("urn:lane2:token:RMT:EU:PSD3:2", "rmt-v2.json", "v2", "2025-06-01T00:00:00Z", "2027-01-01T00:00:00Z", false),
+		"urn:lane2:token:RMT:EU:PSD3:3": entry("urn:lane2:token:RMT:EU:PSD3:3", "rmt-v3.json", "v3", "2025-09-01T00:00:00Z", "2027-01-01T00:00:00Z", false),
+		"urn:lane2:token:RMT:EU:PSD3:4": entry("urn:lane2:token:RMT:EU:PSD3:4", "rmt-revoked.json", "v4", "2025-07-01T00:00:00Z", "2027-01-01T00:00:00Z", true),
+		"urn:lane2:token:IMT:EU:SG:1":   entry("urn:lane2:token:IMT:EU:SG:1", "imt.json", "v1", "2025-01-01T00:00:00Z", "2027-01-01T00:00:00Z", false),
+	}
+	server, err := NewServer(Config{
+		StaticFS: fsys,
+		Tokens:   entries,
+		Now:      func() time.Time { return time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC) },
+	})
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	return server
+}
+
+func TestDiscoveryDocument(t *testing.T) {
+	fsys := fstest.MapFS{
+		"jwks.json": {Data: []byte(`{"keys":[]}`)},
+		"rmt.json":  {Data: []byte(`{"type":"RMT","jurisdiction":["EU"],"domain":"payments_psd3"}`)},
+		"imt.json":  {Data: []byte(`{"type":"IMT","corridor":"EU-SG","domains":["aml_core","payments_psd3"]}`)},
+	}
+	server, err := NewServer(Config{
+		StaticFS: fsys,
+		Tokens: map[string]TokenEntry{
+			"urn:test:rmt": {URI: "urn:test:rmt", Type: "RMT", Filename: "rmt.json"},
+			"urn:test:imt": {URI: "urn:test:imt", Type: "IMT", Filename: "imt.json"},
+		},
+		BaseURL:      "https://reg.example/",
+		IssuerDID:    "did:web:reg.example",
+		PolicyMaxTTL: 6 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/rtgf", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var doc Discovery
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("unmarshal discovery: %v", err)
+	}
+	if doc.Issuer != "did:web:reg.example" || len(doc.TrustAnchors) != 1 || doc.TrustAnchors[0].JWKSURI != "https://reg.example/jwks.json" {
+		t.Fatalf("unexpected issuer/trust anchors: %+v", doc)
+	}
+	if doc.Endpoints["imt"] != "https://reg.example/imt" || doc.Endpoints["revocations"] != "https://reg.example/revocations" ||
+		doc.Endpoints["transparency"] != "https://reg.example/transparency" {
+		t.Fatalf("unexpected endpoints: %+v", doc.Endpoints)
+	}
+	if doc.PolicyMaxTTL != 21600 {
+		t.Fatalf("expected policy_max_ttl 21600, got %d", doc.PolicyMaxTTL)
+	}
+	if strings.Join(doc.SupportedDomains, ",") != "aml_core,payments_psd3" {
+		t.Fatalf("unexpected domains: %v", doc.SupportedDomains)
+	}
+}
+
+func TestTransparencyServesEmptyCheckpoint(t *testing.T) {
+	s := newTestServer(t)
+	cases := []struct {
+		path   string
+		status int
+	}{
+		{"/transparency", http.StatusOK},
+		{"/transparency?since=0", http.StatusOK},
+		{"/transparency?since=-1", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d %s", tc.path, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var head transparency.Checkpoint
+		if err := json.Unmarshal(rec.Body.Bytes(), &head); err != nil {
+			t.Fatalf("unmarshal checkpoint: %v", err)
+		}
+		if head.TreeSize != 0 || head.RootHash != "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" || head.Entries == nil {
+			t.Fatalf("unexpected checkpoint %s", rec.Body.String())
+		}
+	}
+}
+
+func TestDiscoveryMethodNotAllowed(t *testing.T) {
+	s := newTestServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/.well-known/rtgf", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestTokenResponseCacheHeaders(t *testing.T) {
+	fsys := fstest.MapFS{
+		"jwks.json":  {Data: []byte(`{"keys":[]}`)},
+		"token.json": {Data: []byte(`{"type":"RRMT"}`)},
+	}
+	server, err := NewServer(Config{
+		StaticFS: fsys,
+		Tokens: map[string]TokenEntry{
+			"urn:test:token": {URI: "urn:test:token", Type: "RRMT", Filename: "token.json", Hash: "sha256:abc", ExpiresAt: "2025-01-01T02:00:00Z"},
+		},
+		Now:      func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) },
+		CacheTTL: 6 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/tokens?uri=urn:test:token", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"sha256:abc"` {
+		t.Fatalf("unexpected etag %q", etag)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=7200, must-revalidate" {
+		t.Fatalf("expected max-age bounded by exp, got %q", cc)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tokens?uri=urn:test:token", nil)
+	req.Header.Set("If-None-Match", `"sha256:abc"`)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body on 304, got %q", rec.Body.String())
+	}
+}
+
+func TestJWKSAndCatalogConditionalGet(t *testing.T) {
+	s := newTestServer(t)
+	for _, path := range []string{"/jwks.json", "/catalog"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || !strings.HasPrefix(etag, `"sha256:`) {
+			t.Fatalf("%s: expected 200 with etag, got %d %q", path, rec.Code, etag)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=86400, must-revalidate" {
+			t.Fatalf("%s: unexpected cache-control %q", path, cc)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", etag)
+		rec = httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified {
+			t.Fatalf("%s: expected 304, got %d", path, rec.Code)
+		}
+	}
+}
+
+func newVersionedTestServer(t *testing.T) *Server {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"jwks.json":    {Data: []byte(`{"keys":[]}`)},
+		"rrmt-v1.json": {Data: []byte(`{"type":"RRMT","version":"2025.01"}`)},
+		"rrmt-v2.json": {Data: []byte(`{"type":"RRMT","version":"2025.10"}`)},
+		"rrmt-v3.json": {Data: []byte(`{"type":"RRMT","version":"2026.01","revoked":true}`)},
+	}
+	entries := map[string]TokenEntry{
+		"v1": {URI: "urn:lane2:token:RRMT:EU:PSD3:3.1", Type: "RRMT", Slug: "eu-psd3", Filename: "rrmt-v1.json", Hash: "sha256:v1", Version: "2025.01", NotBefore: "2025-01-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z"},
+		"v2": {URI: "urn:lane2:token:RRMT:EU:PSD3:3.2", Type: "RRMT", Slug: "eu-psd3", Filename: "rrmt-v2.json", Hash: "sha256:v2", Version: "2025.10", NotBefore: "2025-10-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z"},
+		"v3": {URI: "urn:lane2:token:RRMT:EU:PSD3:3.2", Type: "RRMT", Slug: "eu-psd3", Filename: "rrmt-v3.json", Hash: "sha256:v3", Version: "2026.01", NotBefore: "2026-01-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z", Revoked: true},
+	}
+	server, err := NewServer(Config{
+		StaticFS: fsys,
+		Tokens:   entries,
+		Now:      func() time.Time { return time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC) },
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return server
+}
+
+func TestTokenVersionHistory(t *testing.T) {
+	s := newVersionedTestServer(t)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tokens/rrmt/eu-psd3/versions", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var history VersionHistory
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil {
+		t.Fatalf("unmarshal history: %v", err)
+	}
+	if history.Current != "2025.10" || len(history.Versions) != 3 {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if history.Versions[0].Version != "2026.01" || history.Versions[2].Version != "2025.01" {
+		t.Fatalf("expected newest-first ordering, got %+v", history.Versions)
+	}
+	if history.Versions[1].Hash != "sha256:v2" || history.Versions[1].NotBefore == "" {
+		t.Fatalf("expected hash and window per version, got %+v", history.Versions[1])
+	}
+}
+
+func TestTokenVersionPinnedLookups(t *testing.T) {
+	s := newVersionedTestServer(t)
+	cases := []struct {
+		path    string
+		status  int
+		version string
+	}{
+		{"/tokens/rrmt/eu-psd3", http.StatusOK, "2025.10"},
+		{"/tokens/rrmt/eu-psd3@2025.01", http.StatusOK, "2025.01"},
+		{"/tokens/rrmt/eu-psd3@2026.01", http.StatusOK, "2026.01"},
+		{"/tokens/rrmt/eu-psd3@1999", http.StatusNotFound, ""},
+		{"/tokens/rrmt/eu-psd3@", http.StatusBadRequest, ""},
+		{"/tokens?uri=urn:lane2:token:RRMT:EU:PSD3:3.2", http.StatusOK, "2025.10"},
+		{"/tokens?uri=urn:lane2:token:RRMT:EU:PSD3:3.2&version=2026.01", http.StatusOK, "2026.01"},
+		{"/tokens?uri=urn:lane2:token:RRMT:EU:PSD3:3.2&version=2025.01", http.StatusNotFound, ""},
+		{"/tokens?uri=urn:lane2:token:RRMT:EU:PSD3:3.1&version=2025.01", http.StatusOK, "2025.01"},
+		{"/tokens?uri=urn:lane2:token:RRMT:EU:PSD3:3.1&version=1999", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.status, rec.Code)
+		}
+		if tc.version != "" && !strings.Contains(rec.Body.String(), `"version":"`+tc.version+`"`) {
+			t.Fatalf("%s: expected version %s, got %s", tc.path, tc.version, rec.Body.String())
+		}
+	}
+}
+
+func TestDuplicateTokenVersionRejected(t *testing.T) {
+	fsys := fstest.MapFS{
+		"jwks.json": {Data: []byte(`{"keys":[]}`)},
+		"a.json":    {Data: []byte(`{}`)},
+	}
+	_, err := NewServer(Config{StaticFS: fsys, Tokens: map[string]TokenEntry{
+		"a": {URI: "urn:x", Filename: "a.json", Version: "1"},
+		"b": {URI: "urn:x", Filename: "a.json", Version: "1"},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "duplicate token") {
+		t.Fatalf("expected duplicate token error, got %v", err)
+	}
+}
+
+func searchTestServer(t *testing.T) *Server {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"jwks.json":  {Data: []byte(`{"keys":[]}`)},
+		"imt-1.json": {Data: []byte(`{"type":"IMT","corridor":"EU->SG","domains":["payments_psd3"]}`)},
+		"imt-2.json": {Data: []byte(`{"type":"IMT","corridor":"EU-SG","domains":["payments_psd3","aml_core"],"iss":"did:web:other"}`)},
+		"imt-3.json": {Data: []byte(`{"type":"IMT","corridor":"SG-MY","domains":["payments_psd3"]}`)},
+		"rrmt.json":  {Data: []byte(`{"type":"RRMT","jurisdiction":["EU"],"domain":"payments_psd3"}`)},
+	}
+	entries := map[string]TokenEntry{
+		"urn:imt:1":  {URI: "urn:imt:1", Type: "IMT", Filename: "imt-1.json", Version: "1", NotBefore: "2025-01-01T00:00:00Z", ExpiresAt: "2026-01-01T00:00:00Z"},
+		"urn:imt:2":  {URI: "urn:imt:2", Type: "IMT", Filename: "imt-2.json", Version: "2", NotBefore: "2026-01-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z"},
+		"urn:imt:3":  {URI: "urn:imt:3", Type: "IMT", Filename: "imt-3.json", Version: "1", NotBefore: "2025-01-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z", Revoked: true},
+		"urn:rrmt:1": {URI: "urn:rrmt:1", Type: "RRMT", Filename: "rrmt.json", Version: "1", NotBefore: "2025-01-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z"},
+	}
+	server, err := NewServer(Config{StaticFS: fsys, Tokens: entries, IssuerDID: "did:web:reg"})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return server
+}
+
+func doSearch(t *testing.T, s *Server, query string) (int, SearchResult) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?"+query, nil))
+	var out SearchResult
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("unmarshal search: %v", err)
+		}
+	}
+	return rec.Code, out
+}
+
+func resultURIs(res SearchResult) string {
+	var uris []string
+	for _, e := range res.Results {
+		uris = append(uris, e.URI)
+	}
+	return strings.Join(uris, ",")
+}
+
+func TestSearchFilters(t *testing.T) {
+	s := searchTestServer(t)
+	cases := map[string]string{
+		"type=imt&corridor=EU-SG&valid_at=2026-03-01": "urn:imt:2",
+		"type=IMT&corridor=eu-sg":                     "urn:imt:1,urn:imt:2",
+		"domain=aml_core":                             "urn:imt:2",
+		"jurisdiction=eu":                             "urn:rrmt:1",
+		"revoked=true":                                "urn:imt:3",
+		"issuer=did:web:reg&type=IMT":                 "urn:imt:1,urn:imt:3",
+		"issuer=did:web:other":                        "urn:imt:2",
+		"valid_at=2025-06-01T00:00:00Z&revoked=false": "urn:imt:1,urn:rrmt:1",
+		"type=PSRT":                                   "",
+	}
+	for query, want := range cases {
+		code, res := doSearch(t, s, query)
+		if code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", query, code)
+		}
+		if got := resultURIs(res); got != want {
+			t.Fatalf("%s: expected %q, got %q", query, want, got)
+		}
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	s := searchTestServer(t)
+	var pages []string
+	query := "limit=3"
+	for i := 0; i < 5; i++ {
+		code, res := doSearch(t, s, query)
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		pages = append(pages, resultURIs(res))
+		if res.NextCursor == "" {
+			break
+		}
+		query = "limit=3&cursor=" + res.NextCursor
+	}
+	if strings.Join(pages, "|") != "urn:imt:1,urn:imt:2,urn:imt:3|urn:rrmt:1" {
+		t.Fatalf("unexpected pages %q", pages)
+	}
+}
+
+func TestSearchInvalidParameters(t *testing.T) {
+	s := searchTestServer(t)
+	for _, query := range []string{"corridor=EUSG", "jurisdiction=EUR", "domain=a/b", "revoked=maybe", "valid_at=yesterday", "limit=0", "limit=1000", "cursor=!!!"} {
+		if code, _ := doSearch(t, s, query); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, code)
+		}
+	}
+}
+
+func integrityTestConfig(mode IntegrityMode) Config {
+	good := []byte(`{"type":"PSRT","version":"1"}`)
+	digest, _ := verifylib.CanonicalDigest(good)
+	return Config{
+		StaticFS: fstest.MapFS{
+			"jwks.json": {Data: []byte(`{"keys":[]}`)},
+			"good.json": {Data: good},
+			"bad.json":  {Data: []byte(`{"type":"CORT","version":"1","hash":"sha256:stale"}`)},
+		},
+		Tokens: map[string]TokenEntry{
+			"urn:t:good": {Type: "PSRT", Slug: "good", Filename: "good.json", Hash: digest, Version: "1"},
+			"urn:t:bad":  {Type: "CORT", Slug: "bad", Filename: "bad.json", Version: "1"},
+		},
+		Integrity:    mode,
+		DigestHeader: true,
+	}
+}
+
+func TestIntegrityStrictRefusesMismatch(t *testing.T) {
+	_, err := NewServer(integrityTestConfig(IntegrityStrict))
+	if err == nil || !strings.Contains(err.Error(), "urn:t:bad") || !errors.Is(err, verifylib.ErrDigestMismatch) {
+		t.Fatalf("expected digest mismatch for urn:t:bad, got %v", err)
+	}
+}
+
+func TestIntegrityQuarantine(t *testing.T) {
+	s, err := NewServer(integrityTestConfig(IntegrityQuarantine))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tokens?uri=urn:t:bad", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected quarantined token to be withheld, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tokens/psrt/good", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got, want := rec.Header().Get(DigestHeader), s.cfg.Tokens["urn:t:good"].Hash; got != want {
+		t.Fatalf("expected digest header %q, got %q", want, got)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var health struct {
+		Status      string             `json:"status"`
+		Quarantined []QuarantinedToken `json:"quarantined"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("unmarshal health: %v", err)
+	}
+	if health.Status != "degraded" || len(health.Quarantined) != 1 || health.Quarantined[0].URI != "urn:t:bad" {
+		t.Fatalf("unexpected health %+v", health)
+	}
+}
+
+func TestParseIntegrityMode(t *testing.T) {
+	if mode, err := ParseIntegrityMode(""); err != nil || mode != IntegrityOff {
+		t.Fatalf("expected off, got %q %v", mode, err)
+	}
+	if mode, err := ParseIntegrityMode("Quarantine"); err != nil || mode != IntegrityQuarantine {
+		t.Fatalf("expected quarantine, got %q %v", mode, err)
+	}
+	if _, err := ParseIntegrityMode("lenient"); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// serveCacheable writes data with a strong ETag and Cache-Control header,
+// answering matching If-None-Match requests with 304 Not Modified.
+func serveCacheable(w http.ResponseWriter, r *http.Request, data []byte, etag string, maxAge time.Duration, contentType string) {
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, must-revalidate", int64(maxAge/time.Second)))
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+// tokenMaxAge bounds the cache lifetime by the configured ceiling and the
+// token's own expiry so caches never hold a token past exp.
+func (s *Server) tokenMaxAge(entry TokenEntry) time.Duration {
+	maxAge := s.cfg.CacheTTL
+	if entry.ExpiresAt == "" {
+		return maxAge
+	}
+	exp, err := time.Parse(time.RFC3339, entry.ExpiresAt)
+	if err != nil {
+		return 0
+	}
+	if remaining := exp.Sub(s.cfg.Now()); remaining < maxAge {
+		return remaining
+	}
+	return maxAge
+}
+
+// entryETag derives a strong ETag from the catalog's sha256 token hash.
+func entryETag(entry TokenEntry) string {
+	if entry.Hash == "" {
+		return ""
+	}
+	return `"` + strings.ReplaceAll(entry.Hash, `"`, "") + `"`
+}
+
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"sha256:` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// encodeJSON mirrors json.Encoder output (including the trailing newline).
+func encodeJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+)
+
+// DefaultRequiredTokens is the token tuple required for corridors derived from the index.
+var DefaultRequiredTokens = []string{"RMT", "IMT", "CORT", "PSRT"}
+
+// CatalogIssuer advertises an issuer and the JWKS verifying its tokens.
+type CatalogIssuer struct {
+	Iss  string `json:"iss"`
+	JWKS string `json:"jwks"`
+}
+
+// CatalogCorridor lists the tokens a corridor requires for execution.
+type CatalogCorridor struct {
+	ID             string   `json:"id"`
+	RequiredTokens []string `json:"requiredTokens"`
+}
+
+// Catalog is the preload document consumed by aARP/SAPP.
+type Catalog struct {
+	RegistrySnapshotID string            `json:"registrySnapshotId"`
+	Issuers            []CatalogIssuer   `json:"issuers"`
+	Corridors          []CatalogCorridor `json:"corridors"`
+	Tokens             []TokenEntry      `json:"tokens"`
+}
+
+// buildCatalog renders the catalog with a snapshot ID computed as the sha256
+// of its canonical encoding (entries in index order, snapshot ID empty).
+func buildCatalog(issuers []CatalogIssuer, corridors []CatalogCorridor, entries []TokenEntry) ([]byte, error) {
+	if corridors == nil {
+		corridors = []CatalogCorridor{}
+	}
+	catalog := Catalog{Issuers: issuers, Corridors: corridors, Tokens: entries}
+	canonical, err := json.Marshal(catalog)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(canonical)
+	catalog.RegistrySnapshotID = "sha256:" + hex.EncodeToString(sum[:])
+	return encodeJSON(catalog)
+}
+
+// collectCorridors derives corridor entries from the published IMTs.
+func collectCorridors(tokens []TokenEntry) []CatalogCorridor {
+	seen := make(map[string]struct{})
+	var corridors []CatalogCorridor
+	for _, entry := range tokens {
+		if !strings.EqualFold(entry.Type, "IMT") || entry.Corridor == "" {
+			continue
+		}
+		if _, ok := seen[entry.Corridor]; ok {
+			continue
+		}
+		seen[entry.Corridor] = struct{}{}
+		required := append([]string(nil), DefaultRequiredTokens...)
+		for _, tokenType := range entry.RequiredTokens {
+			if tokenType = strings.ToUpper(tokenType); !slices.Contains(required, tokenType) {
+				required = append(required, tokenType)
+			}
+		}
+		corridors = append(corridors, CatalogCorridor{ID: entry.Corridor, RequiredTokens: required})
+	}
+	sort.Slice(corridors, func(i, j int) bool { return corridors[i].ID < corridors[j].ID })
+	return corridors
+}
+
+func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Write(w, r, problem.MethodNotAllowed, "")
+		return
+	}
+	catalog := s.current().catalog
+	serveCacheable(w, r, catalog, contentETag(catalog), s.cfg.CacheTTL, "application/json")
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+)
+
+// Discovery is the RTGF-REQ-010 well-known document clients bootstrap from.
+type Discovery struct {
+	Issuer           string            `json:"issuer"`
+	RegistryRoots    []string          `json:"registry_roots"`
+	TrustAnchors     []TrustAnchor     `json:"trust_anchors"`
+	Endpoints        map[string]string `json:"endpoints"`
+	SupportedDomains []string          `json:"supported_domains"`
+	PolicyMaxTTL     int64             `json:"policy_max_ttl"`
+}
+
+// TrustAnchor binds an issuer DID to the JWKS used to verify its tokens.
+type TrustAnchor struct {
+	Iss     string `json:"iss"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+func (s *Server) discovery() Discovery {
+	root := s.baseURL
+	if root == "" {
+		root = "/"
+	}
+	domains := append([]string(nil), s.current().domains...)
+	sort.Strings(domains)
+	return Discovery{
+		Issuer:        s.cfg.IssuerDID,
+		RegistryRoots: []string{root},
+		TrustAnchors: []TrustAnchor{
+			{Iss: s.cfg.IssuerDID, JWKSURI: s.jwksURL},
+		},
+		Endpoints: map[string]string{
+			"rmt":          s.baseURL + "/rmt",
+			"imt":          s.baseURL + "/imt",
+			"revocations":  s.baseURL + "/revocations",
+			"transparency": s.baseURL + "/transparency",
+			"catalog":      s.baseURL + "/catalog",
+			"jwks":         s.jwksURL,
+		},
+		SupportedDomains: domains,
+		PolicyMaxTTL:     int64(s.cfg.PolicyMaxTTL.Seconds()),
+	}
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Write(w, r, problem.MethodNotAllowed, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.discovery()); err != nil {
+		problem.Write(w, r, problem.Internal, err.Error())
+	}
+}
+
+// collectDomains returns the sorted, de-duplicated domain codes of the catalog.
+func collectDomains(tokens []TokenEntry) []string {
+	seen := make(map[string]struct{})
+	var domains []string
+	for _, entry := range tokens {
+		for _, d := range entry.Domains {
+			if _, ok := seen[d]; ok {
+				continue
+			}
+			seen[d] = struct{}{}
+			domains = append(domains, d)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
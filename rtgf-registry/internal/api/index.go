@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/verify"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// index is an immutable snapshot of the published tokens, their payloads and
+// the JWKS. Reload builds a fresh index and swaps it in atomically.
+type index struct {
+	// entries holds every published version, sorted by URI then newest first.
+	entries []TokenEntry
+	// byURI and bySlug group versions newest first.
+	byURI    map[string][]TokenEntry
+	bySlug   map[string][]TokenEntry
+	payloads map[string][]byte
+	jwks     []byte
+	catalog  []byte
+	domains  []string
+	// digests maps a payload file to its verified canonical digest.
+	digests     map[string]string
+	quarantined []QuarantinedToken
+	// verification is the verify service state built from the same catalog,
+	// published in the same store as the index.
+	verification *verify.Snapshot
+}
+
+func (s *Server) current() *index {
+	return s.index.Load()
+}
+
+// Reload validates the token catalog and JWKS currently present in StaticFS
+// and atomically replaces the served index. On error the previous index keeps
+// serving.
+func (s *Server) Reload(tokenCatalog map[string]TokenEntry) error {
+	staged, err := s.Stage(tokenCatalog)
+	if err != nil {
+		return err
+	}
+	s.Publish(staged)
+	return nil
+}
+
+// Stage validates the catalog like Reload without publishing it, so callers
+// can keep other consumers in step with the index (and its quarantine).
+func (s *Server) Stage(tokenCatalog map[string]TokenEntry) (*Staged, error) {
+	if len(tokenCatalog) == 0 {
+		return nil, errors.New("reload: empty token catalog")
+	}
+	idx, err := s.buildIndex(tokenCatalog)
+	if err != nil {
+		return nil, fmt.Errorf("reload: %w", err)
+	}
+	return &Staged{idx: idx}, nil
+}
+
+// Publish atomically replaces the served index, and the verification state
+// attached to it, with a staged one.
+func (s *Server) Publish(staged *Staged) {
+	s.index.Store(staged.idx)
+}
+
+// Verification returns the verification state attached to the served index,
+// or nil when none was attached.
+func (s *Server) Verification() *verify.Snapshot {
+	return s.current().verification
+}
+
+// buildIndex indexes the catalog. Map keys are only used as the URI of
+// entries that leave TokenEntry.URI empty, so several versions of one URI may
+// be supplied under distinct keys.
+func (s *Server) buildIndex(tokenCatalog map[string]TokenEntry) (*index, error) {
+	jwks, err := fs.ReadFile(s.cfg.StaticFS, "jwks.json")
+	if err != nil {
+		return nil, fmt.Errorf("load jwks: %w", err)
+	}
+	if err := validateJWKS(jwks); err != nil {
+		return nil, fmt.Errorf("load jwks: %w", err)
+	}
+
+	idx := &index{
+		entries:  make([]TokenEntry, 0, len(tokenCatalog)),
+		byURI:    make(map[string][]TokenEntry, len(tokenCatalog)),
+		bySlug:   make(map[string][]TokenEntry, len(tokenCatalog)),
+		payloads: make(map[string][]byte, len(tokenCatalog)),
+		jwks:     jwks,
+		digests:  make(map[string]string, len(tokenCatalog)),
+	}
+	var mismatches []error
+	for key, entry := range tokenCatalog {
+		if entry.URI == "" {
+			entry.URI = key
+		}
+		data, ok := idx.payloads[entry.Filename]
+		if !ok {
+			data, err = fs.ReadFile(s.cfg.StaticFS, entry.Filename)
+			if err != nil {
+				return nil, fmt.Errorf("load token %s: %w", entry.URI, err)
+			}
+			if !json.Valid(data) {
+				return nil, fmt.Errorf("load token %s: %s is not valid JSON", entry.URI, entry.Filename)
+			}
+			idx.payloads[entry.Filename] = data
+		}
+		digest, serve, err := s.checkIntegrity(idx, entry, data)
+		if err != nil {
+			mismatches = append(mismatches, err)
+			continue
+		}
+		if !serve {
+			continue
+		}
+		if digest != "" {
+			idx.digests[entry.Filename] = digest
+			if entry.Hash == "" {
+				entry.Hash = digest
+			}
+		}
+		entry = enrichEntry(entry, data)
+		for _, existing := range idx.byURI[entry.URI] {
+			if existing.Version == entry.Version {
+				return nil, fmt.Errorf("duplicate token %s", describeVersion(entry.URI, entry.Version))
+			}
+		}
+		idx.entries = append(idx.entries, entry)
+		idx.byURI[entry.URI] = append(idx.byURI[entry.URI], entry)
+		if entry.Type != "" && entry.Slug != "" {
+			key := slugKey(entry.Type, entry.Slug)
+			idx.bySlug[key] = append(idx.bySlug[key], entry)
+		}
+	}
+	if len(mismatches) > 0 {
+		return nil, fmt.Errorf("integrity check failed: %w", errors.Join(mismatches...))
+	}
+	sort.Slice(idx.quarantined, func(i, j int) bool {
+		if idx.quarantined[i].URI != idx.quarantined[j].URI {
+			return idx.quarantined[i].URI < idx.quarantined[j].URI
+		}
+		return idx.quarantined[i].Version < idx.quarantined[j].Version
+	})
+	sort.Slice(idx.entries, func(i, j int) bool {
+		if idx.entries[i].URI != idx.entries[j].URI {
+			return idx.entries[i].URI < idx.entries[j].URI
+		}
+		return newerThan(idx.entries[i], idx.entries[j])
+	})
+	for _, group := range []map[string][]TokenEntry{idx.byURI, idx.bySlug} {
+		for _, versions := range group {
+			sort.Slice(versions, func(i, j int) bool { return newerThan(versions[i], versions[j]) })
+		}
+	}
+
+	idx.domains = s.cfg.Domains
+	if len(idx.domains) == 0 {
+		idx.domains = collectDomains(idx.entries)
+	}
+	corridors := s.cfg.Corridors
+	if len(corridors) == 0 {
+		corridors = collectCorridors(idx.entries)
+	}
+	idx.catalog, err = buildCatalog(s.cfg.Issuers, corridors, idx.entries)
+	if err != nil {
+		return nil, fmt.Errorf("build catalog: %w", err)
+	}
+	return idx, nil
+}
+
+// lookupURI resolves uri to the pinned version, or to the current version
+// when version is empty. Pins never leave the URI: other versions of its
+// type/slug series are reached through lookupBySlug.
+func (idx *index) lookupURI(uri, version string, now time.Time) (TokenEntry, bool) {
+	versions, ok := idx.byURI[uri]
+	if !ok {
+		return TokenEntry{}, false
+	}
+	if version == "" {
+		return currentVersion(versions, now), true
+	}
+	return pinnedVersion(versions, version)
+}
+
+func (idx *index) lookupBySlug(tokenType, slug, version string, now time.Time) (TokenEntry, error) {
+	versions, ok := idx.bySlug[slugKey(tokenType, slug)]
+	if !ok {
+		return TokenEntry{}, errNotFound
+	}
+	if version == "" {
+		return currentVersion(versions, now), nil
+	}
+	if entry, ok := pinnedVersion(versions, version); ok {
+		return entry, nil
+	}
+	return TokenEntry{}, errNotFound
+}
+
+// currentVersion picks the version verifylib.CurrentVersion selects, so the
+// verifier built from the same catalog checks the version served here.
+func currentVersion(versions []TokenEntry, now time.Time) TokenEntry {
+	infos := make([]verifylib.TokenInfo, len(versions))
+	for i, entry := range versions {
+		infos[i] = entry.info()
+	}
+	return versions[verifylib.CurrentVersion(infos, now)]
+}
+
+func pinnedVersion(versions []TokenEntry, version string) (TokenEntry, bool) {
+	for _, entry := range versions {
+		if entry.Version == version {
+			return entry, true
+		}
+	}
+	return TokenEntry{}, false
+}
+
+func slugKey(tokenType, slug string) string {
+	return strings.ToLower(tokenType) + ":" + slug
+}
+
+func describeVersion(id, version string) string {
+	if version == "" {
+		return id
+	}
+	return id + "@" + version
+}
+
+func validateJWKS(data []byte) error {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("invalid JWKS: %w", err)
+	}
+	if set.Keys == nil {
+		return errors.New("invalid JWKS: missing keys")
+	}
+	return nil
+}
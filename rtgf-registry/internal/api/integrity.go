@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/verify"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// IntegrityMode selects how token digests are enforced when the index is built.
+type IntegrityMode string
+
+const (
+	// IntegrityOff serves tokens without checking their digests.
+	IntegrityOff IntegrityMode = "off"
+	// IntegrityStrict refuses to build an index containing a mismatched token.
+	IntegrityStrict IntegrityMode = "strict"
+	// IntegrityQuarantine withholds mismatched tokens and reports them on /healthz.
+	IntegrityQuarantine IntegrityMode = "quarantine"
+)
+
+// DigestHeader carries the verified canonical digest of a served token when
+// Config.DigestHeader is set.
+const DigestHeader = "X-RTGF-Token-Digest"
+
+// ParseIntegrityMode parses a mode name; the empty string selects IntegrityOff.
+func ParseIntegrityMode(value string) (IntegrityMode, error) {
+	switch mode := IntegrityMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "", IntegrityOff:
+		return IntegrityOff, nil
+	case IntegrityStrict, IntegrityQuarantine:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown integrity mode %q (want off, strict or quarantine)", value)
+	}
+}
+
+// QuarantinedToken records a token withheld because its content did not match
+// its declared hash.
+type QuarantinedToken struct {
+	URI     string `json:"uri"`
+	Version string `json:"version,omitempty"`
+	File    string `json:"file"`
+	Reason  string `json:"reason"`
+}
+
+// Staged is a validated index that has not been published yet.
+type Staged struct {
+	idx *index
+}
+
+// Attach publishes snap with the staged index, so verify.Service reading
+// Server.Verification swaps together with the API.
+func (st *Staged) Attach(snap *verify.Snapshot) {
+	st.idx.verification = snap
+}
+
+// Quarantined lists the tokens withheld from the staged index.
+func (st *Staged) Quarantined() []QuarantinedToken {
+	return append([]QuarantinedToken(nil), st.idx.quarantined...)
+}
+
+// Quarantined lists the tokens withheld from the served index.
+func (s *Server) Quarantined() []QuarantinedToken {
+	return append([]QuarantinedToken(nil), s.current().quarantined...)
+}
+
+// checkIntegrity verifies data against the entry's declared hash and the
+// payload's own hash member. It returns the canonical digest, whether the entry
+// should be served and, in strict mode, the mismatch.
+func (s *Server) checkIntegrity(idx *index, entry TokenEntry, data []byte) (string, bool, error) {
+	if s.cfg.Integrity == IntegrityOff {
+		return "", true, nil
+	}
+	digest, err := verifylib.VerifyDigest(data, entry.Hash)
+	if err == nil {
+		return digest, true, nil
+	}
+	if !errors.Is(err, verifylib.ErrDigestMismatch) || s.cfg.Integrity == IntegrityStrict {
+		return "", false, fmt.Errorf("token %s (%s): %w", describeVersion(entry.URI, entry.Version), entry.Filename, err)
+	}
+	idx.quarantined = append(idx.quarantined, QuarantinedToken{
+		URI:     entry.URI,
+		Version: entry.Version,
+		File:    entry.Filename,
+		Reason:  err.Error(),
+	})
+	return digest, false, nil
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// mediaTypeIMTRMT is the media type registered for RMT/IMT tokens (draft section 9.1).
+const mediaTypeIMTRMT = "application/imt-rmt+json"
+
+func (s *Server) handleRMT(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Write(w, r, problem.MethodNotAllowed, "")
+		return
+	}
+	jurisdiction, domain, ok := splitPathPair(r.URL.Path, "/rmt/")
+	if !ok {
+		problem.Write(w, r, problem.NotFound, "")
+		return
+	}
+	jurisdiction, ok = verifylib.NormalizeJurisdiction(jurisdiction)
+	if !ok {
+		problem.Write(w, r, problem.InvalidRequest, "invalid jurisdiction")
+		return
+	}
+	if !validDomain(domain) {
+		problem.Write(w, r, problem.InvalidRequest, "invalid domain")
+		return
+	}
+	idx := s.current()
+	entry, ok := idx.latestValid(s.cfg.Now(), func(e TokenEntry) bool {
+		return strings.EqualFold(e.Type, "RMT") && verifylib.ContainsFold(e.Jurisdictions, jurisdiction) && slices.Contains(e.Domains, domain)
+	})
+	if !ok {
+		problem.Write(w, r, problem.TokenNotFound, "no valid RMT for "+jurisdiction+"/"+domain)
+		return
+	}
+	s.serveToken(w, r, idx, entry, mediaTypeIMTRMT)
+}
+
+func (s *Server) handleIMT(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Write(w, r, problem.MethodNotAllowed, "")
+		return
+	}
+	corridor, domain, ok := splitPathPair(r.URL.Path, "/imt/")
+	if !ok {
+		problem.Write(w, r, problem.NotFound, "")
+		return
+	}
+	corridor, ok = verifylib.NormalizeCorridor(corridor)
+	if !ok {
+		problem.Write(w, r, problem.InvalidRequest, "invalid corridor: expected SRC-DST")
+		return
+	}
+	if !validDomain(domain) {
+		problem.Write(w, r, problem.InvalidRequest, "invalid domain")
+		return
+	}
+	idx := s.current()
+	entry, ok := idx.latestValid(s.cfg.Now(), func(e TokenEntry) bool {
+		return strings.EqualFold(e.Type, "IMT") && e.Corridor == corridor && slices.Contains(e.Domains, domain)
+	})
+	if !ok {
+		problem.Write(w, r, problem.TokenNotFound, "no valid IMT for "+corridor+"/"+domain)
+		return
+	}
+	s.serveToken(w, r, idx, entry, mediaTypeIMTRMT)
+}
+
+// latestValid returns the newest non-revoked entry matching the predicate
+// whose nbf/exp window contains now.
+func (idx *index) latestValid(now time.Time, match func(TokenEntry) bool) (TokenEntry, bool) {
+	var (
+		best  TokenEntry
+		found bool
+	)
+	for _, entry := range idx.entries {
+		if entry.Revoked || !match(entry) || !withinWindow(entry, now) {
+			continue
+		}
+		if !found || newerThan(entry, best) {
+			best, found = entry, true
+		}
+	}
+	return best, found
+}
+
+func withinWindow(entry TokenEntry, now time.Time) bool {
+	return verifylib.WithinWindow(entry.info(), now)
+}
+
+// newerThan orders entries like verifylib.NewerThan.
+func newerThan(a, b TokenEntry) bool {
+	return verifylib.NewerThan(a.info(), b.info())
+}
+
+func splitPathPair(path, prefix string) (string, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(path, prefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// validDomain enforces the section 9.5 syntax: 1*( ALPHA / DIGIT / "-" / "_" ).
+func validDomain(domain string) bool {
+	if domain == "" {
+		return false
+	}
+	for _, c := range domain {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// enrichEntry fills lookup attributes missing from the catalog entry using the
+// token payload.
+func enrichEntry(entry TokenEntry, data []byte) TokenEntry {
+	var payload struct {
+		Jurisdiction json.RawMessage `json:"jurisdiction"`
+		Corridor     string          `json:"corridor"`
+		Domain       string          `json:"domain"`
+		Domains      []string        `json:"domains"`
+		Iss          string          `json:"iss"`
+		Issuer       string          `json:"issuer"`
+		Required     []string        `json:"required_tokens"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return entry
+	}
+	if entry.Issuer == "" {
+		entry.Issuer = payload.Iss
+		if entry.Issuer == "" {
+			entry.Issuer = payload.Issuer
+		}
+	}
+	if len(entry.Jurisdictions) == 0 {
+		entry.Jurisdictions = verifylib.StringOrList(payload.Jurisdiction)
+	}
+	if entry.Corridor == "" && payload.Corridor != "" {
+		if corridor, ok := verifylib.NormalizeCorridor(payload.Corridor); ok {
+			entry.Corridor = corridor
+		}
+	}
+	if len(entry.RequiredTokens) == 0 {
+		entry.RequiredTokens = payload.Required
+	}
+	if len(entry.Domains) == 0 {
+		entry.Domains = append(entry.Domains, payload.Domains...)
+		if payload.Domain != "" && !slices.Contains(entry.Domains, payload.Domain) {
+			entry.Domains = append(entry.Domains, payload.Domain)
+		}
+	}
+	return entry
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+)
+
+// SearchResult is one page of catalog entries matching a search.
+type SearchResult struct {
+	Results    []TokenEntry `json:"results"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// searchFilter holds the parsed /search query. Zero values match everything.
+type searchFilter struct {
+	tokenType    string
+	jurisdiction string
+	corridor     string
+	domain       string
+	issuer       string
+	revoked      *bool
+	validAt      *time.Time
+}
+
+func (f searchFilter) match(entry TokenEntry, defaultIssuer string) bool {
+	if f.tokenType != "" && !strings.EqualFold(entry.Type, f.tokenType) {
+		return false
+	}
+	if f.jurisdiction != "" && !verifylib.ContainsFold(entry.Jurisdictions, f.jurisdiction) {
+		return false
+	}
+	if f.corridor != "" && entry.Corridor != f.corridor {
+		return false
+	}
+	if f.domain != "" && !slices.Contains(entry.Domains, f.domain) {
+		return false
+	}
+	if f.issuer != "" {
+		issuer := entry.Issuer
+		if issuer == "" {
+			issuer = defaultIssuer
+		}
+		if issuer != f.issuer {
+			return false
+		}
+	}
+	if f.revoked != nil && entry.Revoked != *f.revoked {
+		return false
+	}
+	if f.validAt != nil && !withinWindow(entry, *f.validAt) {
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problem.Write(w, r, problem.MethodNotAllowed, "")
+		return
+	}
+	query := r.URL.Query()
+	filter, err := parseSearchFilter(query.Get)
+	if err != nil {
+		problem.Write(w, r, problem.InvalidRequest, err.Error())
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := query.Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			problem.Write(w, r, problem.InvalidRequest, "limit must be between 1 and "+strconv.Itoa(maxSearchLimit))
+			return
+		}
+	}
+	idx := s.current()
+	start := 0
+	if raw := query.Get("cursor"); raw != "" {
+		start, err = idx.cursorStart(raw)
+		if err != nil {
+			problem.Write(w, r, problem.InvalidRequest, "invalid cursor")
+			return
+		}
+	}
+
+	result := SearchResult{Results: []TokenEntry{}}
+	for i := start; i < len(idx.entries); i++ {
+		entry := idx.entries[i]
+		if !filter.match(entry, s.cfg.IssuerDID) {
+			continue
+		}
+		if len(result.Results) == limit {
+			result.NextCursor = encodeCursor(result.Results[limit-1])
+			break
+		}
+		result.Results = append(result.Results, entry)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	data, err := encodeJSON(result)
+	if err != nil {
+		problem.Write(w, r, problem.Internal, err.Error())
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+type errInvalidFilter string
+
+func (e errInvalidFilter) Error() string { return string(e) }
+
+func parseSearchFilter(get func(string) string) (searchFilter, error) {
+	var f searchFilter
+	if v := strings.TrimSpace(get("type")); v != "" {
+		f.tokenType = strings.ToUpper(v)
+	}
+	if v := strings.TrimSpace(get("jurisdiction")); v != "" {
+		jurisdiction, ok := verifylib.NormalizeJurisdiction(v)
+		if !ok {
+			return f, errInvalidFilter("invalid jurisdiction")
+		}
+		f.jurisdiction = jurisdiction
+	}
+	if v := strings.TrimSpace(get("corridor")); v != "" {
+		corridor, ok := verifylib.NormalizeCorridor(v)
+		if !ok {
+			return f, errInvalidFilter("invalid corridor: expected SRC-DST")
+		}
+		f.corridor = corridor
+	}
+	if v := strings.TrimSpace(get("domain")); v != "" {
+		if !validDomain(v) {
+			return f, errInvalidFilter("invalid domain")
+		}
+		f.domain = v
+	}
+	f.issuer = strings.TrimSpace(get("issuer"))
+	if v := strings.TrimSpace(get("revoked")); v != "" {
+		revoked, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, errInvalidFilter("revoked must be true or false")
+		}
+		f.revoked = &revoked
+	}
+	if v := strings.TrimSpace(get("valid_at")); v != "" {
+		at, err := parseInstant(v)
+		if err != nil {
+			return f, errInvalidFilter("valid_at must be an RFC 3339 timestamp or YYYY-MM-DD date")
+		}
+		f.validAt = &at
+	}
+	return f, nil
+}
+
+// parseInstant accepts RFC 3339 timestamps and bare dates (midnight UTC).
+func parseInstant(value string) (time.Time, error) {
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		return ts, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// encodeCursor identifies the last returned entry so the next page resumes
+// after it even if the index has been reloaded in between.
+func encodeCursor(entry TokenEntry) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(entry.URI + "\x00" + entry.Version))
+}
+
+func (idx *index) cursorStart(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	uri, version, ok := strings.Cut(string(raw), "\x00")
+	if !ok {
+		return 0, errInvalidFilter("malformed cursor")
+	}
+	for i, entry := range idx.entries {
+		if entry.URI == uri && entry.Version == version {
+			return i + 1, nil
+		}
+		if entry.URI > uri {
+			return i, nil
+		}
+	}
+	return len(idx.entries), nil
+}
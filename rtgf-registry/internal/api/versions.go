@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+)
+
+// VersionHistory lists every published version of a type/slug series.
+type VersionHistory struct {
+	Type     string       `json:"type"`
+	Slug     string       `json:"slug"`
+	Current  string       `json:"current"`
+	Versions []TokenEntry `json:"versions"`
+}
+
+func (s *Server) serveVersions(w http.ResponseWriter, r *http.Request, idx *index, tokenType, slug string) {
+	versions, ok := idx.bySlug[slugKey(tokenType, slug)]
+	if !ok {
+		problem.Write(w, r, problem.TokenNotFound, "no "+tokenType+" token with slug "+slug)
+		return
+	}
+	history := VersionHistory{
+		Type:     versions[0].Type,
+		Slug:     slug,
+		Current:  currentVersion(versions, s.cfg.Now()).Version,
+		Versions: versions,
+	}
+	data, err := encodeJSON(history)
+	if err != nil {
+		problem.Write(w, r, problem.Internal, err.Error())
+		return
+	}
+	serveCacheable(w, r, data, contentETag(data), s.cfg.CacheTTL, "application/json")
+}
@@ -1,3 +1,83 @@
+// Package crypto holds the registry's signing keys.
 package crypto
 
-// TODO: implement JWKS loading, Ed25519 signing, and future hybrid PQ support.
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TODO: future hybrid PQ support.
+
+// Signer produces compact EdDSA JWS with one registry key.
+type Signer struct {
+	kid string
+	key ed25519.PrivateKey
+}
+
+// NewSigner wraps an Ed25519 private key published in the registry JWKS
+// under kid.
+func NewSigner(kid string, key ed25519.PrivateKey) (*Signer, error) {
+	if kid == "" {
+		return nil, errors.New("signing key requires a kid")
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("signing key is not an Ed25519 private key")
+	}
+	return &Signer{kid: kid, key: key}, nil
+}
+
+// LoadSigner reads a private OKP/Ed25519 JWK (`kid`, `d` and optionally `x`).
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var jwk struct {
+		Kty string `json:"kty"`
+		Crv string `json:"crv"`
+		Kid string `json:"kid"`
+		D   string `json:"d"`
+		X   string `json:"x"`
+	}
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("invalid signing JWK: %w", err)
+	}
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" {
+		return nil, fmt.Errorf("invalid signing JWK: want OKP/Ed25519, got %s/%s", jwk.Kty, jwk.Crv)
+	}
+	seed, err := base64.RawURLEncoding.DecodeString(jwk.D)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid signing JWK: d is not an Ed25519 seed")
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	if jwk.X != "" && jwk.X != base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)) {
+		return nil, errors.New("invalid signing JWK: x does not match d")
+	}
+	return NewSigner(jwk.Kid, key)
+}
+
+// Kid returns the JWKS key id of the signing key.
+func (s *Signer) Kid() string {
+	return s.kid
+}
+
+// Public returns the verification key.
+func (s *Signer) Public() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign returns payload as a compact JWS with protected header
+// {"alg":"EdDSA","kid":…,"typ":typ}.
+func (s *Signer) Sign(payload []byte, typ string) string {
+	header, _ := json.Marshal(struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ,omitempty"`
+	}{"EdDSA", s.kid, typ})
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(s.key, []byte(input)))
+}
@@ -0,0 +1,50 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+func TestLoadSignerAndSign(t *testing.T) {
+	seed := []byte("rtgf-registry-crypto-test-seed-1")
+	pub := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	d := base64.RawURLEncoding.EncodeToString(seed)
+	x := base64.RawURLEncoding.EncodeToString(pub)
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+
+	signer, err := LoadSigner(write("key.json", `{"kty":"OKP","crv":"Ed25519","kid":"k1","d":"`+d+`","x":"`+x+`"}`))
+	if err != nil {
+		t.Fatalf("LoadSigner: %v", err)
+	}
+	if signer.Kid() != "k1" || !signer.Public().Equal(pub) {
+		t.Fatalf("unexpected signer %s %x", signer.Kid(), signer.Public())
+	}
+	jws := signer.Sign([]byte(`{"ok":true}`), "test+jws")
+	payload, err := verifylib.VerifyJWS([]byte(jws), verifylib.KeySet{"k1": pub})
+	if err != nil || string(payload) != `{"ok":true}` {
+		t.Fatalf("VerifyJWS: %s %v", payload, err)
+	}
+
+	for name, body := range map[string]string{
+		"no kid":     `{"kty":"OKP","crv":"Ed25519","d":"` + d + `"}`,
+		"wrong x":    `{"kty":"OKP","crv":"Ed25519","kid":"k1","d":"` + d + `","x":"` + base64.RawURLEncoding.EncodeToString(make([]byte, 32)) + `"}`,
+		"short seed": `{"kty":"OKP","crv":"Ed25519","kid":"k1","d":"AAAA"}`,
+		"ec key":     `{"kty":"EC","crv":"P-256","kid":"k1","d":"` + d + `"}`,
+	} {
+		if _, err := LoadSigner(write("bad.json", body)); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
@@ -40,8 +40,8 @@ func TestVerifyEndpointHappyPath(t *testing.T) {
 
 func TestVerifyEndpointRevokedToken(t *testing.T) {
 	fs := defaultFS()
-	fs["rrmt-eu-psd3-2025.json"] = &fstest.MapFile{
-		Data: []byte(`{"type":"RRMT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":true}`),
+	fs["rmt-eu-psd3-2025.json"] = &fstest.MapFile{
+		Data: []byte(`{"type":"RMT","rmt_id":"urn:lane2:token:RMT:EU:PSD3:3.2","jurisdiction":"EU",` + mandateClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":true}`),
 	}
 	server := newIntegrationServer(t, fs)
 	defer server.Close()
@@ -53,10 +53,14 @@ func TestVerifyEndpointRevokedToken(t *testing.T) {
 		PSRT: "urn:lane2:token:PSRT:VISA:ACQ-123",
 	})
 
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected problem+json, got %q", ct)
 	}
 	var body struct {
+		Type   string `json:"type"`
 		Valid  bool   `json:"valid"`
 		Reason string `json:"reason"`
 	}
@@ -64,6 +68,9 @@ func TestVerifyEndpointRevokedToken(t *testing.T) {
 	if body.Valid {
 		t.Fatalf("expected invalid response for revoked token")
 	}
+	if body.Type != "https://lane2.ai/ietf/imt-rmt/errors#token_revoked" {
+		t.Fatalf("unexpected problem type %s", body.Type)
+	}
 	if want := "token_revoked"; body.Reason == "" || !strings.Contains(body.Reason, want) {
 		t.Fatalf("expected reason containing %q, got %s", want, body.Reason)
 	}
@@ -71,8 +78,8 @@ func TestVerifyEndpointRevokedToken(t *testing.T) {
 
 func TestVerifyEndpointInvalidType(t *testing.T) {
 	fs := defaultFS()
-	fs["rrmt-eu-psd3-2025.json"] = &fstest.MapFile{
-		Data: []byte(`{"type":"CORT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`),
+	fs["rmt-eu-psd3-2025.json"] = &fstest.MapFile{
+		Data: []byte(`{"type":"RRMT",` + rrmtClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`),
 	}
 	server := newIntegrationServer(t, fs)
 	defer server.Close()
@@ -84,10 +91,11 @@ func TestVerifyEndpointInvalidType(t *testing.T) {
 		PSRT: "urn:lane2:token:PSRT:VISA:ACQ-123",
 	})
 
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", resp.StatusCode)
 	}
 	var body struct {
+		Type   string `json:"type"`
 		Valid  bool   `json:"valid"`
 		Reason string `json:"reason"`
 	}
@@ -95,8 +103,11 @@ func TestVerifyEndpointInvalidType(t *testing.T) {
 	if body.Valid {
 		t.Fatalf("expected invalid response for type mismatch")
 	}
-	if body.Reason != "invalid_rrmt" {
-		t.Fatalf("expected invalid_rrmt reason, got %s", body.Reason)
+	if body.Type != "https://lane2.ai/ietf/imt-rmt/errors#token_type_invalid" {
+		t.Fatalf("unexpected problem type %s", body.Type)
+	}
+	if body.Reason != "invalid_rmt" {
+		t.Fatalf("expected invalid_rmt reason, got %s", body.Reason)
 	}
 }
 
@@ -148,6 +159,47 @@ func TestJWKSRotation(t *testing.T) {
 	}
 }
 
+func TestVerifyEndpointInvalidCORTTerms(t *testing.T) {
+	fs := defaultFS()
+	fs["cort-vodafone-visa-2025.json"] = &fstest.MapFile{
+		Data: []byte(`{"type":"CORT","references":{"rrmt":"urn:lane2:token:RRMT:EU:PSD3:3.2"},"parties":[{"id":"did:org:vodafone","role":"merchant_of_record"},{"id":"did:org:visa","role":"scheme"}],` +
+			`"splits":[{"party":"did:org:vodafone","pct":0.8},{"party":"did:org:broker","pct":0.4}],` +
+			`"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`),
+	}
+	server := newIntegrationServer(t, fs)
+	defer server.Close()
+
+	resp := doVerifyRequest(t, server, verifyPayload{
+		RMT:  "urn:lane2:token:RMT:EU:PSD3:3.2",
+		IMT:  "urn:lane2:token:IMT:EU:SG:2025",
+		CORT: "urn:lane2:token:CORT:VODAFONE.VISA:2025",
+		PSRT: "urn:lane2:token:PSRT:VISA:ACQ-123",
+	})
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", resp.StatusCode)
+	}
+	var body struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+		Checks []struct {
+			Role   string `json:"role"`
+			Terms  string `json:"terms"`
+			Reason string `json:"reason"`
+		} `json:"checks"`
+	}
+	decodeBody(t, resp, &body)
+	if body.Type != "https://lane2.ai/ietf/imt-rmt/errors#cort_terms_invalid" {
+		t.Fatalf("unexpected problem type %s", body.Type)
+	}
+	if body.Reason != "cort_split_party:urn:lane2:token:CORT:VODAFONE.VISA:2025" {
+		t.Fatalf("expected cort_split_party reason, got %s", body.Reason)
+	}
+	if cort := body.Checks[2]; cort.Role != "cort" || cort.Terms != "fail" {
+		t.Fatalf("unexpected cort check %+v", cort)
+	}
+}
+
 // helpers
 
 type verifyPayload struct {
@@ -196,7 +248,7 @@ func newIntegrationServer(t *testing.T, fsys fstest.MapFS) *httptest.Server {
 	if err != nil {
 		t.Fatalf("NewStaticVerifier: %v", err)
 	}
-	verifyService := verify.NewService(1, staticVerifier)
+	verifyService := verify.NewService(1, staticVerifier, verify.Options{})
 
 	mux := http.NewServeMux()
 	mux.Handle("/", apiServer)
@@ -207,15 +259,27 @@ func newIntegrationServer(t *testing.T, fsys fstest.MapFS) *httptest.Server {
 	return httptest.NewServer(mux)
 }
 
+// Schema-required claims shared by the RMT and IMT fixtures, and the minimal
+// RRMT and PSRT claims.
+const (
+	mandateClaims = `"domain":"payments_psd3","effective_date":"2025-10-01T00:00:00Z","expires_at":"2026-10-01T00:00:00Z","policy_snapshot_hash":"sha256:test"`
+	rrmtClaims    = `"domain":"payments_psd3","jurisdiction":["EU"],"currency":"EUR","pricing":{"mode":"per_txn"}`
+	psrtClaims    = `"acquirer":"did:org:visa","scheme":"VISA","capture":{"mode":"auto"}`
+)
+
 func defaultFS() fstest.MapFS {
 	return fstest.MapFS{
-		"rrmt-eu-psd3-2025.json": {Data: []byte(`{"type":"RRMT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
-		"imt-eu-sg-2025.json":    {Data: []byte(`{"type":"IMT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"rrmt-eu-psd3-2025.json": {Data: []byte(`{"type":"RRMT",` + rrmtClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"rmt-eu-psd3-2025.json":  {Data: []byte(`{"type":"RMT","rmt_id":"urn:lane2:token:RMT:EU:PSD3:3.2","jurisdiction":"EU",` + mandateClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"rmt-sg-psd3-2025.json":  {Data: []byte(`{"type":"RMT","rmt_id":"urn:lane2:token:RMT:SG:PSD3:3.2","jurisdiction":"SG",` + mandateClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"imt-eu-sg-2025.json": {
+			Data: []byte(`{"type":"IMT","imt_id":"urn:lane2:token:IMT:EU:SG:2025",` + mandateClaims + `,"corridor":"EU-SG","references":{"rmt_a":"urn:lane2:token:RMT:EU:PSD3:3.2","rmt_b":"urn:lane2:token:RMT:SG:PSD3:3.2"},"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`),
+		},
 		"cort-vodafone-visa-2025.json": {
-			Data: []byte(`{"type":"CORT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`),
+			Data: []byte(`{"type":"CORT","references":{"rrmt":"urn:lane2:token:RRMT:EU:PSD3:3.2"},"parties":[{"id":"did:org:vodafone","role":"merchant_of_record"},{"id":"did:org:visa","role":"scheme"}],"splits":[{"party":"did:org:vodafone","pct":0.95},{"party":"did:org:visa","pct":0.05}],"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`),
 		},
 		"psrt-visa-acq-123.json": {
-			Data: []byte(`{"type":"PSRT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`),
+			Data: []byte(`{"type":"PSRT",` + psrtClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`),
 		},
 		"jwks.json": {
 			Data: []byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"test-key-1","x":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}]}`),
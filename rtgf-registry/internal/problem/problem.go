@@ -0,0 +1,105 @@
+// Package problem renders RFC 9457 problem details with the stable error type
+// identifiers registered for IMT/RMT registries (draft section 9.6).
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type for problem detail responses.
+const ContentType = "application/problem+json"
+
+// TypeBase prefixes every problem type identifier.
+const TypeBase = "https://lane2.ai/ietf/imt-rmt/errors#"
+
+// Type is a registered error code with its HTTP status and human title.
+type Type struct {
+	Code   string
+	Status int
+	Title  string
+}
+
+// URI returns the stable type identifier, e.g. TypeBase + "imt_verification_failed".
+func (t Type) URI() string {
+	return TypeBase + t.Code
+}
+
+// Registered problem types.
+var (
+	InvalidRequest        = Type{"invalid_request", http.StatusBadRequest, "Invalid request"}
+	NotFound              = Type{"not_found", http.StatusNotFound, "Resource not found"}
+	MethodNotAllowed      = Type{"method_not_allowed", http.StatusMethodNotAllowed, "Method not allowed"}
+	Internal              = Type{"internal_error", http.StatusInternalServerError, "Internal server error"}
+	IMTVerificationFailed = Type{"imt_verification_failed", http.StatusForbidden, "IMT verification failed"}
+	MissingToken          = Type{"missing_token", http.StatusBadRequest, "Required token missing"}
+	TokenNotFound         = Type{"token_not_found", http.StatusNotFound, "Token not found"}
+	TokenMalformed        = Type{"token_malformed", http.StatusUnprocessableEntity, "Token metadata malformed"}
+	TokenRevoked          = Type{"token_revoked", http.StatusForbidden, "Token revoked"}
+	TokenExpired          = Type{"token_expired", http.StatusForbidden, "Token expired"}
+	TokenNotYetValid      = Type{"token_not_yet_valid", http.StatusForbidden, "Token not yet valid"}
+	TokenTypeInvalid      = Type{"token_type_invalid", http.StatusForbidden, "Token type invalid"}
+	TokenSignatureInvalid = Type{"token_signature_invalid", http.StatusForbidden, "Token signature invalid"}
+	TokenReplayed         = Type{"token_replayed", http.StatusForbidden, "Token replay detected"}
+	CORTTermsInvalid      = Type{"cort_terms_invalid", http.StatusForbidden, "CORT commercial terms invalid"}
+	EvidenceMissing       = Type{"evidence_missing", http.StatusForbidden, "Required evidence missing"}
+)
+
+// Details is an RFC 9457 problem details object. Extensions are serialised as
+// additional top-level members.
+type Details struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// New builds problem details for the registered type.
+func New(t Type, detail string) *Details {
+	return &Details{Type: t.URI(), Title: t.Title, Status: t.Status, Detail: detail}
+}
+
+// With adds an extension member and returns the receiver for chaining.
+func (d *Details) With(key string, value any) *Details {
+	if d.Extensions == nil {
+		d.Extensions = make(map[string]any)
+	}
+	d.Extensions[key] = value
+	return d
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *Details) MarshalJSON() ([]byte, error) {
+	out := make(map[string]any, len(d.Extensions)+5)
+	for k, v := range d.Extensions {
+		out[k] = v
+	}
+	out["type"] = d.Type
+	out["title"] = d.Title
+	out["status"] = d.Status
+	if d.Detail != "" {
+		out["detail"] = d.Detail
+	}
+	if d.Instance != "" {
+		out["instance"] = d.Instance
+	}
+	return json.Marshal(out)
+}
+
+// Write serialises the problem with its status code.
+func (d *Details) Write(w http.ResponseWriter, r *http.Request) {
+	if d.Instance == "" && r != nil && r.URL != nil {
+		d.Instance = r.URL.Path
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(d.Status)
+	_ = json.NewEncoder(w).Encode(d)
+}
+
+// Write is shorthand for New(t, detail).Write(w, r).
+func Write(w http.ResponseWriter, r *http.Request, t Type, detail string) {
+	New(t, detail).Write(w, r)
+}
@@ -0,0 +1,47 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/imt/EU-SG/payments", nil)
+
+	New(IMTVerificationFailed, "token expired").With("reason", "token_expired").Write(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if body["type"] != "https://lane2.ai/ietf/imt-rmt/errors#imt_verification_failed" {
+		t.Fatalf("unexpected type %v", body["type"])
+	}
+	if body["status"] != float64(403) || body["instance"] != "/imt/EU-SG/payments" || body["reason"] != "token_expired" {
+		t.Fatalf("unexpected body %v", body)
+	}
+}
+
+func TestMarshalOmitsEmptyMembers(t *testing.T) {
+	data, err := json.Marshal(New(NotFound, ""))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var body map[string]any
+	_ = json.Unmarshal(data, &body)
+	if _, ok := body["detail"]; ok {
+		t.Fatalf("expected detail to be omitted: %s", data)
+	}
+	if _, ok := body["instance"]; ok {
+		t.Fatalf("expected instance to be omitted: %s", data)
+	}
+}
@@ -0,0 +1,174 @@
+// Package reload re-reads the token directory and swaps the registry API and
+// verifier indexes together, keeping the previous index on validation failure.
+package reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/api"
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/verify"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// Loader resolves the current token catalog (manifest, scan or defaults).
+type Loader func() (verifylib.Catalog, error)
+
+// MandalaPath is where the static root, the parent of the token directory,
+// announces the Mandala proof types the registry accepts as evidence.
+const MandalaPath = ".well-known/mandala.json"
+
+// Reloader rebuilds the served token index on demand.
+type Reloader struct {
+	fsys        fs.FS
+	load        Loader
+	server      *api.Server
+	service     *verify.Service
+	mandalaFS   fs.FS
+	mandalaPath string
+}
+
+// New returns a Reloader for the given static filesystem and consumers. The
+// service reads its verification state from the server's index from then on.
+func New(fsys fs.FS, load Loader, server *api.Server, service *verify.Service) (*Reloader, error) {
+	if fsys == nil || load == nil || server == nil || service == nil {
+		return nil, errors.New("reload: filesystem, loader, server and service are required")
+	}
+	service.ReadSnapshotsFrom(server.Verification)
+	return &Reloader{fsys: fsys, load: load, server: server, service: service, mandalaFS: fsys, mandalaPath: MandalaPath}, nil
+}
+
+// SetMandalaSource reads the Mandala announcement from path in fsys instead
+// of MandalaPath in the reloaded filesystem.
+func (r *Reloader) SetMandalaSource(fsys fs.FS, path string) {
+	r.mandalaFS, r.mandalaPath = fsys, path
+}
+
+// Reload validates every token, the JWKS and the Mandala announcement before
+// swapping, and refuses a catalog whose Mandala requirements no announced
+// proof type could satisfy. The verifier, registry keys and announcement are
+// attached to the staged API index and published with it in one atomic store,
+// so the API and /verify see the new catalog together or not at all; tokens
+// quarantined by the API index are withheld from the verifier as well.
+func (r *Reloader) Reload() (verifylib.Catalog, error) {
+	catalog, err := r.load()
+	if err != nil {
+		return nil, fmt.Errorf("load catalog: %w", err)
+	}
+	staged, err := r.server.Stage(api.TokensFromCatalog(catalog))
+	if err != nil {
+		return nil, err
+	}
+	catalog = WithoutQuarantined(catalog, staged.Quarantined())
+	verifier, err := verifylib.NewStaticVerifierFromCatalog(r.fsys, ".", catalog, r.server.Now())
+	if err != nil {
+		return nil, fmt.Errorf("init static verifier: %w", err)
+	}
+	mandala, err := LoadMandala(r.mandalaFS, r.mandalaPath)
+	if err != nil {
+		return nil, fmt.Errorf("load mandala: %w", err)
+	}
+	if err := CheckMandala(verifier, catalog, mandala); err != nil {
+		return nil, err
+	}
+	staged.Attach(&verify.Snapshot{Verifier: verifier, RegistryKeys: RegistryKeys(r.fsys), Mandala: mandala})
+	r.server.Publish(staged)
+	return catalog, nil
+}
+
+// LoadMandala reads the Mandala announcement at path in fsys. A missing
+// announcement yields the zero Mandala, under which every Mandala evidence
+// requirement fails.
+func LoadMandala(fsys fs.FS, path string) (verify.Mandala, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return verify.Mandala{}, nil
+	}
+	if err != nil {
+		return verify.Mandala{}, err
+	}
+	mandala, err := verify.ParseMandala(data)
+	if err != nil {
+		return verify.Mandala{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return mandala, nil
+}
+
+// RegistryKeys returns the Ed25519 keys in the registry's jwks.json, which
+// verify inline tokens the registry issued. A JWKS without usable Ed25519
+// keys yields nil: the API still serves it, but nothing it signs is trusted.
+func RegistryKeys(fsys fs.FS) verifylib.KeySet {
+	data, err := fs.ReadFile(fsys, "jwks.json")
+	if err != nil {
+		return nil
+	}
+	keys, err := verifylib.ParseJWKS(data)
+	if err != nil {
+		return nil
+	}
+	return keys
+}
+
+// CheckMandala fails when a catalog token declares a Mandala evidence
+// requirement but the announcement lists no proof types, since every such
+// requirement would then fail.
+func CheckMandala(verifier verify.TokenVerifier, catalog verifylib.Catalog, mandala verify.Mandala) error {
+	if len(mandala.ProofTypes) > 0 {
+		return nil
+	}
+	for _, entry := range catalog {
+		if requirements := verify.MandalaRequirements(verifier, entry.URI); len(requirements) > 0 {
+			return fmt.Errorf("token %s requires %s but no Mandala announcement lists supported proof types", entry.URI, requirements[0])
+		}
+	}
+	return nil
+}
+
+// WithoutQuarantined drops quarantined token versions from catalog.
+func WithoutQuarantined(catalog verifylib.Catalog, quarantined []api.QuarantinedToken) verifylib.Catalog {
+	if len(quarantined) == 0 {
+		return catalog
+	}
+	return catalog.Filter(func(entry verifylib.CatalogEntry) bool {
+		for _, q := range quarantined {
+			if q.URI == entry.URI && q.Version == entry.Version {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Run reloads on every interval tick (when interval > 0) and whenever trigger
+// fires (e.g. SIGHUP) until ctx is cancelled. Failures are logged and the
+// previous index keeps serving.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration, trigger <-chan os.Signal) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-trigger:
+		}
+		catalog, err := r.Reload()
+		if err != nil {
+			log.Printf("reload failed, keeping previous index: %v", err)
+			continue
+		}
+		log.Printf("reloaded token index (%d tokens)", len(catalog))
+		for _, q := range r.server.Quarantined() {
+			log.Printf("quarantined token %s: %s", q.URI, q.Reason)
+		}
+	}
+}
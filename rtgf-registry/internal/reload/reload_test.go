@@ -0,0 +1,225 @@
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/api"
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/verify"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+const (
+	window = `"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z"`
+	// mandate holds the claims the RMT and IMT schemas require besides an id.
+	mandate = `"domain":"payments_psd3","effective_date":"2025-10-01T00:00:00Z","expires_at":"2026-10-01T00:00:00Z","policy_snapshot_hash":"sha256:test"`
+)
+
+func newFixture(t *testing.T) (fstest.MapFS, *api.Server, *verify.Service, *Reloader) {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"jwks.json":   {Data: []byte(`{"keys":[{"kid":"k1"}]}`)},
+		"rmt.json":    {Data: []byte(`{"type":"RMT","uri":"urn:t:rmt","rmt_id":"urn:t:rmt","jurisdiction":"EU","version":"v1",` + mandate + `,` + window + `}`)},
+		"rmt-sg.json": {Data: []byte(`{"type":"RMT","uri":"urn:t:rmt-sg","rmt_id":"urn:t:rmt-sg","jurisdiction":"SG","version":"v1",` + mandate + `,` + window + `}`)},
+		"imt.json":    {Data: []byte(`{"type":"IMT","uri":"urn:t:imt","imt_id":"urn:t:imt",` + mandate + `,"corridor":"EU-SG","references":{"rmt_a":"urn:t:rmt","rmt_b":"urn:t:rmt-sg"},` + window + `}`)},
+		"cort.json":   {Data: []byte(`{"type":"CORT","uri":"urn:t:cort","references":{},"parties":[{"id":"did:org:m","role":"merchant_of_record"},{"id":"did:org:s","role":"scheme"}],"splits":[{"party":"did:org:m","pct":0.95},{"party":"did:org:s","pct":0.05}],` + window + `}`)},
+		"psrt.json":   {Data: []byte(`{"type":"PSRT","uri":"urn:t:psrt","acquirer":"did:org:acq","scheme":"VISA","capture":{"mode":"auto"},` + window + `}`)},
+	}
+	load := func() (verifylib.Catalog, error) { return verifylib.ScanCatalog(fsys, ".") }
+	catalog, err := load()
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	server, err := api.NewServer(api.Config{StaticFS: fsys, Tokens: api.TokensFromCatalog(catalog)})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	verifier, err := verifylib.NewStaticVerifierFromCatalog(fsys, ".", catalog, server.Now())
+	if err != nil {
+		t.Fatalf("NewStaticVerifierFromCatalog: %v", err)
+	}
+	service := verify.NewService(1, verifier, verify.Options{})
+	reloader, err := New(fsys, load, server, service)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return fsys, server, service, reloader
+}
+
+func tokenBody(t *testing.T, server *api.Server, uri string) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tokens?uri="+uri, nil))
+	return rec.Body.String()
+}
+
+func verifyValid(t *testing.T, service *verify.Service) bool {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"tokens": map[string]string{
+		"rmt": "urn:t:rmt", "imt": "urn:t:imt", "cort": "urn:t:cort", "psrt": "urn:t:psrt",
+	}})
+	rec := httptest.NewRecorder()
+	service.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+	var out verify.VerifyResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &out)
+	return out.Valid
+}
+
+func TestReloadSwapsBothIndexes(t *testing.T) {
+	fsys, server, service, reloader := newFixture(t)
+	fsys["rmt.json"] = &fstest.MapFile{Data: []byte(`{"type":"RMT","uri":"urn:t:rmt","rmt_id":"urn:t:rmt","jurisdiction":"EU","version":"v2","revoked":true,` + mandate + `,` + window + `}`)}
+
+	if _, err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if body := tokenBody(t, server, "urn:t:rmt"); !bytes.Contains([]byte(body), []byte(`"v2"`)) {
+		t.Fatalf("expected reloaded payload, got %s", body)
+	}
+	if verifyValid(t, service) {
+		t.Fatalf("expected verifier to see revoked token after reload")
+	}
+}
+
+func TestReloadPublishesVerificationWithIndex(t *testing.T) {
+	fsys, server, service, reloader := newFixture(t)
+	if server.Verification() != nil {
+		t.Fatalf("expected no attached verification before the first reload")
+	}
+	fsys["rmt.json"] = &fstest.MapFile{Data: []byte(`{"type":"RMT","uri":"urn:t:rmt","rmt_id":"urn:t:rmt","jurisdiction":"EU","version":"v2","revoked":true,` + mandate + `,` + window + `}`)}
+	catalog, err := verifylib.ScanCatalog(fsys, ".")
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	staged, err := server.Stage(api.TokensFromCatalog(catalog))
+	if err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	verifier, err := verifylib.NewStaticVerifierFromCatalog(fsys, ".", catalog, server.Now())
+	if err != nil {
+		t.Fatalf("NewStaticVerifierFromCatalog: %v", err)
+	}
+	staged.Attach(&verify.Snapshot{Verifier: verifier})
+	if !verifyValid(t, service) {
+		t.Fatalf("expected the service to ignore state that was staged but not published")
+	}
+	server.Publish(staged)
+	if verifyValid(t, service) {
+		t.Fatalf("expected the service to read the verifier published with the index")
+	}
+	if _, err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if snap := server.Verification(); snap == nil || snap.Verifier == nil {
+		t.Fatalf("expected reload to attach its verification state, got %+v", snap)
+	}
+}
+
+func TestReloadKeepsPreviousIndexOnFailure(t *testing.T) {
+	cases := map[string]func(fstest.MapFS){
+		"brokenToken": func(fsys fstest.MapFS) {
+			fsys["rmt.json"] = &fstest.MapFile{Data: []byte(`{"type":"RMT",`)}
+		},
+		"brokenJWKS": func(fsys fstest.MapFS) {
+			fsys["rmt.json"] = &fstest.MapFile{Data: []byte(`{"type":"RMT","uri":"urn:t:rmt","rmt_id":"urn:t:rmt","jurisdiction":"EU","version":"v2","revoked":true,` + mandate + `}`)}
+			fsys["jwks.json"] = &fstest.MapFile{Data: []byte(`not json`)}
+		},
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			fsys, server, service, reloader := newFixture(t)
+			mutate(fsys)
+
+			if _, err := reloader.Reload(); err == nil {
+				t.Fatalf("expected reload error")
+			}
+			if body := tokenBody(t, server, "urn:t:rmt"); !bytes.Contains([]byte(body), []byte(`"v1"`)) {
+				t.Fatalf("expected previous payload, got %s", body)
+			}
+			if !verifyValid(t, service) {
+				t.Fatalf("expected previous verifier to keep serving")
+			}
+		})
+	}
+}
+
+func TestNewRequiresDependencies(t *testing.T) {
+	if _, err := New(nil, nil, nil, nil); err == nil {
+		t.Fatalf("expected error for missing dependencies")
+	}
+}
+
+func TestReloadWithholdsQuarantinedTokens(t *testing.T) {
+	fsys, _, service, _ := newFixture(t)
+	load := func() (verifylib.Catalog, error) { return verifylib.ScanCatalog(fsys, ".") }
+	catalog, _ := load()
+	server, err := api.NewServer(api.Config{StaticFS: fsys, Tokens: api.TokensFromCatalog(catalog), Integrity: api.IntegrityQuarantine})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	reloader, err := New(fsys, load, server, service)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	fsys["cort.json"] = &fstest.MapFile{Data: []byte(`{"type":"CORT","uri":"urn:t:cort","references":{},"parties":[{"id":"did:org:m","role":"merchant_of_record"},{"id":"did:org:s","role":"scheme"}],"splits":[{"party":"did:org:m","pct":0.95},{"party":"did:org:s","pct":0.05}],"hash":"sha256:stale",` + window + `}`)}
+
+	catalog, err = reloader.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if _, ok := catalog.Lookup("urn:t:cort", server.Now()); ok {
+		t.Fatalf("expected quarantined token to be dropped from the catalog")
+	}
+	if q := server.Quarantined(); len(q) != 1 || q[0].URI != "urn:t:cort" {
+		t.Fatalf("unexpected quarantine %+v", q)
+	}
+	if verifyValid(t, service) {
+		t.Fatalf("expected verifier to withhold the quarantined token")
+	}
+}
+
+func TestReloadPicksUpMandalaAnnouncement(t *testing.T) {
+	fsys, _, service, reloader := newFixture(t)
+	fsys["rmt.json"] = &fstest.MapFile{Data: []byte(`{"type":"RMT","uri":"urn:t:rmt","rmt_id":"urn:t:rmt","jurisdiction":"EU","version":"v2","evidence_requirements":["E-SANCTIONS_PROOF@mandala:zkp"],` + mandate + `,` + window + `}`)}
+	withProof := func() bool {
+		body, _ := json.Marshal(map[string]any{
+			"tokens": map[string]string{"rmt": "urn:t:rmt", "imt": "urn:t:imt", "cort": "urn:t:cort", "psrt": "urn:t:psrt"},
+			"mandala_proofs": []map[string]any{{
+				"proof_type": "zkp_sanctions", "ccid": "ccid-1", "provider": "https://mandala.example", "version": "1",
+				"proof_hash": "sha256:" + strings.Repeat("ab", 32), "verified": true,
+			}},
+		})
+		rec := httptest.NewRecorder()
+		service.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+		var out verify.VerifyResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &out)
+		return out.Valid
+	}
+
+	if _, err := reloader.Reload(); err == nil {
+		t.Fatalf("expected reload error for a Mandala requirement without an announcement")
+	}
+	if !verifyValid(t, service) {
+		t.Fatalf("expected previous verifier to keep serving")
+	}
+
+	fsys[MandalaPath] = &fstest.MapFile{Data: []byte(`{"supported_proof_types":["zkp_sanctions"]}`)}
+	if _, err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !withProof() {
+		t.Fatalf("expected announced proof type to satisfy the requirement")
+	}
+
+	fsys[MandalaPath] = &fstest.MapFile{Data: []byte(`not json`)}
+	if _, err := reloader.Reload(); err == nil {
+		t.Fatalf("expected reload error for a malformed announcement")
+	}
+	if !withProof() {
+		t.Fatalf("expected previous announcement to keep serving")
+	}
+}
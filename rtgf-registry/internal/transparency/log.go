@@ -1,3 +1,48 @@
+// Package transparency serves the registry's transparency log (draft section
+// 6, GET /transparency?since=).
 package transparency
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+)
+
 // TODO: implement Merkle log, checkpoints, and proofs for token issuance/revocation events.
+
+// Checkpoint is the log head returned by /transparency together with the
+// entries appended after the requested tree size.
+type Checkpoint struct {
+	TreeSize uint64            `json:"tree_size"`
+	RootHash string            `json:"root_hash"`
+	Entries  []json.RawMessage `json:"entries"`
+}
+
+// emptyRoot is the RFC 6962 Merkle tree hash of an empty log.
+var emptyRoot = func() string {
+	sum := sha256.Sum256(nil)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}()
+
+// Handler serves the log. No events are logged yet, so every request sees
+// the empty checkpoint; `since` must still be a non-negative tree size.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem.Write(w, r, problem.MethodNotAllowed, "")
+			return
+		}
+		if since := r.URL.Query().Get("since"); since != "" {
+			if _, err := strconv.ParseUint(since, 10, 64); err != nil {
+				problem.Write(w, r, problem.InvalidRequest, "since must be a non-negative tree size")
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Checkpoint{RootHash: emptyRoot, Entries: []json.RawMessage{}})
+	})
+}
@@ -0,0 +1,26 @@
+package verify
+
+import (
+	"errors"
+
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// validateAML requires the optional AML screening (AMLS) and verification
+// (AMLV) tokens when the IMT's corridor lists them in `required_tokens`. When
+// submitted they are window- and type-checked like the rest of the tuple.
+func validateAML(verifier TokenVerifier, req VerifyRequest) error {
+	var imt struct {
+		RequiredTokens []string `json:"required_tokens"`
+	}
+	if err := decodeToken(verifier, req.Tokens.IMT, &imt); err != nil {
+		return err
+	}
+	if req.Tokens.AMLS == "" && verifylib.ContainsFold(imt.RequiredTokens, "AMLS") {
+		return errors.New("missing_amls")
+	}
+	if req.Tokens.AMLV == "" && verifylib.ContainsFold(imt.RequiredTokens, "AMLV") {
+		return errors.New("missing_amlv")
+	}
+	return nil
+}
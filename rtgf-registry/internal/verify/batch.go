@@ -0,0 +1,69 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+)
+
+const (
+	// DefaultBatchWorkers bounds concurrent evaluation of a batch.
+	DefaultBatchWorkers = 8
+	// MaxBatchSize caps the number of requests accepted by /verify/batch.
+	MaxBatchSize = 1000
+)
+
+// BatchResponse carries one result per submitted request, in request order.
+// Every result is evaluated against the same revEpoch and token index.
+type BatchResponse struct {
+	RevEpoch uint64           `json:"revEpoch"`
+	Results  []VerifyResponse `json:"results"`
+}
+
+// HandleVerifyBatch evaluates a JSON array of VerifyRequests concurrently on
+// a bounded worker pool. Item failures are reported in their result; the
+// response is 200 unless the batch itself is malformed.
+func (s *Service) HandleVerifyBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		problem.Write(w, r, problem.MethodNotAllowed, "")
+		return
+	}
+	defer r.Body.Close()
+	var reqs []VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		problem.Write(w, r, problem.InvalidRequest, "batch must be a JSON array of verify requests")
+		return
+	}
+	if len(reqs) == 0 || len(reqs) > MaxBatchSize {
+		problem.Write(w, r, problem.InvalidRequest, fmt.Sprintf("batch must hold between 1 and %d requests", MaxBatchSize))
+		return
+	}
+
+	// Snapshot the revocation epoch and token index once for the whole batch.
+	revEpoch := s.revEpoch.Load()
+	snap := s.current()
+	ctx := r.Context()
+
+	results := make([]VerifyResponse, len(reqs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := min(s.batchWorkers, len(reqs)); n > 0; n-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = s.response(s.evaluate(ctx, snap, reqs[i]), revEpoch, reqs[i])
+			}
+		}()
+	}
+	for i := range reqs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	respondJSON(w, BatchResponse{RevEpoch: revEpoch, Results: results})
+}
@@ -0,0 +1,62 @@
+package verify
+
+import (
+	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/crypto"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// DefaultSkew is the clock skew tolerated around nbf/exp (RTGF-REQ-020).
+const DefaultSkew = 120 * time.Second
+
+// Clock supplies the instant tokens are verified at.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to Clock.
+type ClockFunc func() time.Time
+
+// Now implements Clock.
+func (f ClockFunc) Now() time.Time { return f() }
+
+// SystemClock reads the wall clock in UTC.
+var SystemClock Clock = ClockFunc(func() time.Time { return time.Now().UTC() })
+
+// FixedClock always reports t, for deterministic runs.
+func FixedClock(t time.Time) Clock {
+	t = t.UTC()
+	return ClockFunc(func() time.Time { return t })
+}
+
+// Options tunes time handling, batch concurrency, inline token trust, replay
+// tracking and receipt signing in the verify service.
+type Options struct {
+	// Clock defaults to SystemClock.
+	Clock Clock
+	// Skew widens every validity window on both ends; zero selects DefaultSkew
+	// and a negative value disables the tolerance.
+	Skew time.Duration
+	// AllowHistorical honours VerifyRequest.At. Leave it off in production so
+	// callers cannot verify against a past instant to bypass expiry.
+	AllowHistorical bool
+	// BatchWorkers bounds concurrent evaluation in /verify/batch; defaults to
+	// DefaultBatchWorkers.
+	BatchWorkers int
+	// RegistryKeys and TrustedKeys verify inline signed tokens. RegistryKeys
+	// is the registry's own JWKS and is replaced by each reload's Snapshot;
+	// TrustedKeys holds partner registries' keys.
+	RegistryKeys verifylib.KeySet
+	TrustedKeys  verifylib.KeySet
+	// Replay tracks observed jti values; defaults to an in-memory LRU.
+	Replay verifylib.ReplayCache
+	// Signer, when set, signs a Receipt into every response; Issuer is the
+	// receipt's `iss`.
+	Signer *crypto.Signer
+	Issuer string
+	// Mandala lists the proof types accepted as evidence for RMT/IMT
+	// evidence_requirements and is replaced by each reload's Snapshot; with
+	// none, Mandala requirements always fail.
+	Mandala Mandala
+}
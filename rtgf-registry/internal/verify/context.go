@@ -0,0 +1,203 @@
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// ExecutionContext describes the transaction a token tuple is presented for
+// (RTGF-REQ-020 step 5). Every field is optional; only supplied fields are
+// matched.
+type ExecutionContext struct {
+	Corridor string `json:"corridor,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Payer    string `json:"payer,omitempty"`
+	Payee    string `json:"payee,omitempty"`
+	// Amount is a decimal string, e.g. "125.50".
+	Amount   string `json:"amount,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// Scheme is the payment scheme, matched against the PSRT scheme.
+	Scheme string `json:"scheme,omitempty"`
+}
+
+type imtContext struct {
+	Corridor string          `json:"corridor"`
+	Domain   string          `json:"domain"`
+	Domains  json.RawMessage `json:"domains"`
+}
+
+type rrmtContext struct {
+	Jurisdiction json.RawMessage `json:"jurisdiction"`
+	Domain       string          `json:"domain"`
+	Currency     string          `json:"currency"`
+	Pricing      rrmtPricing     `json:"pricing"`
+}
+
+type rrmtPricing struct {
+	Mode  string `json:"mode"`
+	Tiers []struct {
+		Min    json.Number `json:"min"`
+		Max    json.Number `json:"max"`
+		FeePct json.Number `json:"fee_pct"`
+		MinFee json.Number `json:"min_fee"`
+	} `json:"tiers"`
+	Surcharges []struct {
+		Code string      `json:"code"`
+		Pct  json.Number `json:"pct"`
+	} `json:"surcharges"`
+}
+
+// tier returns the index of the first tier whose inclusive [min, max] bounds
+// hold amount. A missing bound is open.
+func (p rrmtPricing) tier(amount *big.Rat) (int, bool) {
+	for i, tier := range p.Tiers {
+		lower, okMin := new(big.Rat).SetString(tier.Min.String())
+		upper, okMax := new(big.Rat).SetString(tier.Max.String())
+		if okMin && amount.Cmp(lower) < 0 {
+			continue
+		}
+		if okMax && amount.Cmp(upper) > 0 {
+			continue
+		}
+		return i, true
+	}
+	return 0, false
+}
+
+type cortContext struct {
+	Parties []struct {
+		ID string `json:"id"`
+	} `json:"parties"`
+}
+
+type psrtContext struct {
+	Acquirer string `json:"acquirer"`
+	Scheme   string `json:"scheme"`
+}
+
+// validateContext matches the request's execution context against the IMT
+// corridor and domains, the RRMT jurisdiction, domain, currency and pricing
+// tiers, the CORT parties (including the PSRT acquirer) and the PSRT scheme.
+// A CORT without parties matches no payer, payee or acquirer. The RRMT is the
+// submitted one, or the one the CORT references.
+func validateContext(provider TokenVerifier, req VerifyRequest) error {
+	ec := req.Context
+	if ec == nil {
+		return nil
+	}
+	corridor := ""
+	if ec.Corridor != "" {
+		normalized, ok := verifylib.NormalizeCorridor(ec.Corridor)
+		if !ok {
+			return errors.New("invalid_context:corridor")
+		}
+		corridor = normalized
+	}
+	var amount *big.Rat
+	if ec.Amount != "" {
+		value, ok := new(big.Rat).SetString(ec.Amount)
+		if !ok || value.Sign() < 0 {
+			return errors.New("invalid_context:amount")
+		}
+		amount = value
+	}
+
+	var imt imtContext
+	if err := decodeToken(provider, req.Tokens.IMT, &imt); err != nil {
+		return err
+	}
+	if corridor != "" {
+		if got, _ := verifylib.NormalizeCorridor(imt.Corridor); got != corridor {
+			return errors.New("context_mismatch:corridor")
+		}
+	}
+	if ec.Domain != "" {
+		domains := append(verifylib.StringOrList(imt.Domains), imt.Domain)
+		if !verifylib.ContainsFold(domains, ec.Domain) {
+			return errors.New("context_mismatch:domain")
+		}
+	}
+
+	var cort cortContext
+	if err := decodeToken(provider, req.Tokens.CORT, &cort); err != nil {
+		return err
+	}
+	if err := validateRRMTContext(provider, req, corridor, amount); err != nil {
+		return err
+	}
+	parties := make([]string, 0, len(cort.Parties))
+	for _, party := range cort.Parties {
+		parties = append(parties, party.ID)
+	}
+	if ec.Payer != "" && !slices.Contains(parties, ec.Payer) {
+		return errors.New("context_mismatch:payer")
+	}
+	if ec.Payee != "" && !slices.Contains(parties, ec.Payee) {
+		return errors.New("context_mismatch:payee")
+	}
+	var psrt psrtContext
+	if err := decodeToken(provider, req.Tokens.PSRT, &psrt); err != nil {
+		return err
+	}
+	if psrt.Acquirer != "" && !slices.Contains(parties, psrt.Acquirer) {
+		return errors.New("context_mismatch:acquirer")
+	}
+	if ec.Scheme != "" && !strings.EqualFold(psrt.Scheme, ec.Scheme) {
+		return errors.New("context_mismatch:scheme")
+	}
+	return nil
+}
+
+func validateRRMTContext(provider TokenVerifier, req VerifyRequest, corridor string, amount *big.Rat) error {
+	ec := req.Context
+	uri := req.Tokens.RRMT
+	if uri == "" {
+		refs, err := loadReferences(provider, req.Tokens.CORT)
+		if err != nil {
+			return err
+		}
+		uri = refs.References.RRMT
+	}
+	if uri == "" {
+		return nil
+	}
+	var rrmt rrmtContext
+	if err := decodeToken(provider, uri, &rrmt); err != nil {
+		return err
+	}
+	if jurisdictions := verifylib.StringOrList(rrmt.Jurisdiction); corridor != "" && len(jurisdictions) > 0 {
+		src, dst, _ := strings.Cut(corridor, "-")
+		if !verifylib.ContainsFold(jurisdictions, src) && !verifylib.ContainsFold(jurisdictions, dst) {
+			return errors.New("context_mismatch:jurisdiction")
+		}
+	}
+	if ec.Domain != "" && rrmt.Domain != "" && !strings.EqualFold(rrmt.Domain, ec.Domain) {
+		return errors.New("context_mismatch:domain")
+	}
+	if ec.Currency != "" && rrmt.Currency != "" && !strings.EqualFold(rrmt.Currency, ec.Currency) {
+		return errors.New("context_mismatch:currency")
+	}
+	if amount != nil && len(rrmt.Pricing.Tiers) > 0 {
+		if _, ok := rrmt.Pricing.tier(amount); !ok {
+			return errors.New("context_mismatch:amount")
+		}
+	}
+	return nil
+}
+
+func decodeToken(provider TokenVerifier, uri string, v any) error {
+	payload, ok := provider.Token(uri)
+	if !ok {
+		return fmt.Errorf("metadata_missing:%s", uri)
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("metadata_invalid:%s", uri)
+	}
+	return nil
+}
@@ -4,65 +4,146 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
-	"os"
-	"strings"
 	"sync/atomic"
 	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/crypto"
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
 )
 
 type Service struct {
-	revEpoch atomic.Uint64
-	verifier TokenVerifier
+	revEpoch     atomic.Uint64
+	initial      *Snapshot
+	snapshots    func() *Snapshot
+	trustedKeys  verifylib.KeySet
+	replay       verifylib.ReplayCache
+	signer       *crypto.Signer
+	issuer       string
+	clock        Clock
+	skew         time.Duration
+	historical   bool
+	batchWorkers int
+}
+
+// Snapshot is the reloadable state a request is verified against: the token
+// verifier, the registry's own signing keys and the Mandala announcement.
+// A request reads one Snapshot, so it never mixes state from two reloads.
+type Snapshot struct {
+	Verifier     TokenVerifier
+	RegistryKeys verifylib.KeySet
+	Mandala      Mandala
 }
 
 type VerifyRequest struct {
-	Tokens struct {
-		RMT  string `json:"rmt"`
-		IMT  string `json:"imt"`
-		CORT string `json:"cort"`
-		PSRT string `json:"psrt"`
-		AMLS string `json:"amls,omitempty"`
-		AMLV string `json:"amlv,omitempty"`
-	} `json:"tokens"`
+	Tokens TokenSlots `json:"tokens"`
+	// Context optionally describes the execution context the tokens must match.
+	Context *ExecutionContext `json:"context,omitempty"`
+	// At requests verification as of an RFC 3339 instant; honoured only when
+	// the service allows historical verification.
+	At string `json:"at,omitempty"`
+	// Proofs are the Mandala proof receipts satisfying the RMT/IMT
+	// evidence_requirements.
+	Proofs []MandalaProof `json:"mandala_proofs,omitempty"`
 }
 
 type VerifyResponse struct {
 	Valid    bool   `json:"valid"`
 	RevEpoch uint64 `json:"revEpoch"`
 	Reason   string `json:"reason,omitempty"`
+	// At echoes the instant of a historical verification.
+	At string `json:"at,omitempty"`
+	// Checks reports each submitted token in a fixed role order.
+	Checks []TokenCheck `json:"checks,omitempty"`
+	// Receipt is a registry-signed compact JWS over a Receipt, present when
+	// the service has a signing key.
+	Receipt string `json:"receipt,omitempty"`
 }
 
 type RevocationResponse struct {
 	RevEpoch uint64 `json:"revEpoch"`
 }
 
-func NewService(initial uint64, verifier TokenVerifier) *Service {
-	s := &Service{verifier: verifier}
+func NewService(initial uint64, verifier TokenVerifier, opts Options) *Service {
+	s := &Service{
+		clock:        opts.Clock,
+		skew:         opts.Skew,
+		historical:   opts.AllowHistorical,
+		batchWorkers: opts.BatchWorkers,
+		trustedKeys:  opts.TrustedKeys,
+		replay:       opts.Replay,
+		signer:       opts.Signer,
+		issuer:       opts.Issuer,
+	}
+	if s.clock == nil {
+		s.clock = SystemClock
+	}
+	if s.replay == nil {
+		s.replay = verifylib.NewMemoryReplayCache(verifylib.DefaultReplayCapacity)
+	}
+	if s.batchWorkers <= 0 {
+		s.batchWorkers = DefaultBatchWorkers
+	}
+	switch {
+	case s.skew == 0:
+		s.skew = DefaultSkew
+	case s.skew < 0:
+		s.skew = 0
+	}
 	s.revEpoch.Store(initial)
+	s.initial = &Snapshot{Verifier: verifier, RegistryKeys: opts.RegistryKeys, Mandala: opts.Mandala}
 	return s
 }
 
+// ReadSnapshotsFrom makes every later request take its state from source,
+// such as the registry index that reload publishes the verifier with, so the
+// API and verification swap in one atomic store. Until source yields a
+// Snapshot the state NewService was built with applies. Call it before the
+// service handles requests.
+func (s *Service) ReadSnapshotsFrom(source func() *Snapshot) {
+	s.snapshots = source
+}
+
+// current returns the state for one request.
+func (s *Service) current() *Snapshot {
+	if s.snapshots != nil {
+		if snap := s.snapshots(); snap != nil {
+			return snap
+		}
+	}
+	return s.initial
+}
+
+// keys returns the snapshot's registry keys merged with the configured
+// trusted keys.
+func (s *Service) keys(snap *Snapshot) verifylib.KeySet {
+	return snap.RegistryKeys.Merge(s.trustedKeys)
+}
+
 func (s *Service) HandleVerify(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		problem.Write(w, r, problem.MethodNotAllowed, "")
 		return
 	}
 	defer r.Body.Close()
 	var req VerifyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondJSON(w, VerifyResponse{Valid: false, RevEpoch: s.revEpoch.Load(), Reason: "invalid_request"})
+		respondFailure(w, r, VerifyResponse{Valid: false, RevEpoch: s.revEpoch.Load(), Reason: "invalid_request"})
+		return
+	}
+	res := s.evaluate(r.Context(), s.current(), req)
+	resp := s.response(res, s.revEpoch.Load(), req)
+	if !resp.Valid {
+		respondFailure(w, r, resp)
 		return
 	}
-	valid, reason := s.validateTokens(r.Context(), req)
-	resp := VerifyResponse{Valid: valid, RevEpoch: s.revEpoch.Load(), Reason: reason}
 	respondJSON(w, resp)
 }
 
 func (s *Service) HandleRevocationsGet(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		problem.Write(w, r, problem.MethodNotAllowed, "")
 		return
 	}
 	respondJSON(w, RevocationResponse{RevEpoch: s.revEpoch.Load()})
@@ -70,42 +151,13 @@ func (s *Service) HandleRevocationsGet(w http.ResponseWriter, r *http.Request) {
 
 func (s *Service) HandleRevocationsBump(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		problem.Write(w, r, problem.MethodNotAllowed, "")
 		return
 	}
 	s.revEpoch.Add(1)
 	respondJSON(w, RevocationResponse{RevEpoch: s.revEpoch.Load()})
 }
 
-func (s *Service) validateTokens(ctx context.Context, req VerifyRequest) (bool, string) {
-	required := map[string]string{
-		"rmt":  req.Tokens.RMT,
-		"imt":  req.Tokens.IMT,
-		"cort": req.Tokens.CORT,
-		"psrt": req.Tokens.PSRT,
-	}
-	for key, value := range required {
-		if strings.TrimSpace(value) == "" {
-			return false, fmt.Sprintf("missing_%s", key)
-		}
-	}
-	if s.verifier != nil {
-		if err := validateWindows(currentTime(), s.verifier, req); err != nil {
-			return false, err.Error()
-		}
-		if err := s.verifier.VerifyRRMT(ctx, req.Tokens.RMT); err != nil {
-			return false, "invalid_rrmt"
-		}
-		if err := s.verifier.VerifyCORT(ctx, req.Tokens.CORT); err != nil {
-			return false, "invalid_cort"
-		}
-		if err := s.verifier.VerifyPSRT(ctx, req.Tokens.PSRT); err != nil {
-			return false, "invalid_psrt"
-		}
-	}
-	return true, ""
-}
-
 func respondJSON(w http.ResponseWriter, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(v)
@@ -115,59 +167,11 @@ var ErrInvalidMethod = errors.New("invalid method")
 
 type TokenVerifier interface {
 	VerifyRRMT(ctx context.Context, uri string) error
+	VerifyRMT(ctx context.Context, uri string) error
+	VerifyIMT(ctx context.Context, uri string) error
 	VerifyCORT(ctx context.Context, uri string) error
 	VerifyPSRT(ctx context.Context, uri string) error
+	VerifyAMLS(ctx context.Context, uri string) error
+	VerifyAMLV(ctx context.Context, uri string) error
 	Token(uri string) (json.RawMessage, bool)
 }
-
-func validateWindows(now time.Time, provider TokenVerifier, req VerifyRequest) error {
-	type tokenMeta struct {
-		NotBefore string `json:"nbf"`
-		Expires   string `json:"exp"`
-		Revoked   bool   `json:"revoked"`
-	}
-	for _, uri := range []string{req.Tokens.RMT, req.Tokens.IMT, req.Tokens.CORT, req.Tokens.PSRT} {
-		if uri == "" {
-			continue
-		}
-		payload, ok := provider.Token(uri)
-		if !ok || len(payload) == 0 {
-			return fmt.Errorf("metadata_missing:%s", uri)
-		}
-		var meta tokenMeta
-		if err := json.Unmarshal(payload, &meta); err != nil {
-			return fmt.Errorf("metadata_invalid:%s", uri)
-		}
-		if meta.Revoked {
-			return fmt.Errorf("token_revoked:%s", uri)
-		}
-		if meta.NotBefore != "" {
-			nbf, err := time.Parse(time.RFC3339, meta.NotBefore)
-			if err != nil {
-				return fmt.Errorf("invalid_nbf:%s", uri)
-			}
-			if now.Before(nbf) {
-				return fmt.Errorf("token_not_yet_valid:%s", uri)
-			}
-		}
-		if meta.Expires != "" {
-			exp, err := time.Parse(time.RFC3339, meta.Expires)
-			if err != nil {
-				return fmt.Errorf("invalid_exp:%s", uri)
-			}
-			if now.After(exp) {
-				return fmt.Errorf("token_expired:%s", uri)
-			}
-		}
-	}
-	return nil
-}
-
-func currentTime() time.Time {
-	if fixed := os.Getenv("FIXED_TIME"); fixed != "" {
-		if ts, err := time.Parse(time.RFC3339, fixed); err == nil {
-			return ts.UTC()
-		}
-	}
-	return time.Now().UTC()
-}
@@ -3,22 +3,47 @@ package verify
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/crypto"
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
 )
 
 type stubVerifier struct {
 	verifyErr error
+	imtErr    error
+	amlvErr   error
 	tokens    map[string]string
 }
 
 func (s *stubVerifier) VerifyRRMT(ctx context.Context, uri string) error { return s.verifyErr }
+func (s *stubVerifier) VerifyRMT(ctx context.Context, uri string) error  { return s.verifyErr }
+func (s *stubVerifier) VerifyIMT(ctx context.Context, uri string) error {
+	if s.imtErr != nil {
+		return s.imtErr
+	}
+	return s.verifyErr
+}
 func (s *stubVerifier) VerifyCORT(ctx context.Context, uri string) error { return s.verifyErr }
 func (s *stubVerifier) VerifyPSRT(ctx context.Context, uri string) error { return s.verifyErr }
+func (s *stubVerifier) VerifyAMLS(ctx context.Context, uri string) error { return s.verifyErr }
+func (s *stubVerifier) VerifyAMLV(ctx context.Context, uri string) error {
+	if s.amlvErr != nil {
+		return s.amlvErr
+	}
+	return s.verifyErr
+}
 func (s *stubVerifier) Token(uri string) (json.RawMessage, bool) {
 	if s.tokens == nil {
 		return nil, false
@@ -33,14 +58,15 @@ func (s *stubVerifier) Token(uri string) (json.RawMessage, bool) {
 func happyTokens() map[string]string {
 	return map[string]string{
 		"urn:lane2:token:RMT:EU:PSD3:3.2":         `{"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`,
-		"urn:lane2:token:IMT:EU:SG:2025":          `{"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`,
+		"urn:lane2:token:RMT:SG:PSD3:3.2":         `{"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`,
+		"urn:lane2:token:IMT:EU:SG:2025":          `{"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false,"references":{"rmt_a":"urn:lane2:token:RMT:EU:PSD3:3.2","rmt_b":"urn:lane2:token:RMT:SG:PSD3:3.2"}}`,
 		"urn:lane2:token:CORT:VODAFONE.VISA:2025": `{"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`,
 		"urn:lane2:token:PSRT:VISA:ACQ-123":       `{"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`,
 	}
 }
 
 func TestVerifyHandler(t *testing.T) {
-	svc := NewService(42, &stubVerifier{tokens: happyTokens()})
+	svc := NewService(42, &stubVerifier{tokens: happyTokens()}, Options{})
 	payload := VerifyRequest{}
 	payload.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
 	payload.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
@@ -64,7 +90,7 @@ func TestVerifyHandler(t *testing.T) {
 }
 
 func TestVerifyMissingToken(t *testing.T) {
-	svc := NewService(1, &stubVerifier{tokens: happyTokens()})
+	svc := NewService(1, &stubVerifier{tokens: happyTokens()}, Options{})
 	payload := VerifyRequest{}
 	payload.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
 	payload.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
@@ -82,7 +108,7 @@ func TestVerifyMissingToken(t *testing.T) {
 func TestVerifyExpiredToken(t *testing.T) {
 	tokens := happyTokens()
 	tokens["urn:lane2:token:RMT:EU:PSD3:3.2"] = `{"nbf":"2000-01-01T00:00:00Z","exp":"2001-01-01T00:00:00Z","revoked":false}`
-	svc := NewService(1, &stubVerifier{tokens: tokens})
+	svc := NewService(1, &stubVerifier{tokens: tokens}, Options{})
 	payload := VerifyRequest{}
 	payload.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
 	payload.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
@@ -99,7 +125,7 @@ func TestVerifyExpiredToken(t *testing.T) {
 }
 
 func TestVerifyMethodNotAllowed(t *testing.T) {
-	svc := NewService(1, &stubVerifier{})
+	svc := NewService(1, &stubVerifier{}, Options{})
 	rec := httptest.NewRecorder()
 	svc.HandleVerify(rec, httptest.NewRequest(http.MethodGet, "/verify", nil))
 	if rec.Code != http.StatusMethodNotAllowed {
@@ -108,7 +134,7 @@ func TestVerifyMethodNotAllowed(t *testing.T) {
 }
 
 func TestRevocationBump(t *testing.T) {
-	svc := NewService(1, &stubVerifier{})
+	svc := NewService(1, &stubVerifier{}, Options{})
 	rec := httptest.NewRecorder()
 	svc.HandleRevocationsBump(rec, httptest.NewRequest(http.MethodPost, "/revocations/bump", nil))
 
@@ -120,7 +146,7 @@ func TestRevocationBump(t *testing.T) {
 }
 
 func TestRevocationBumpMethodNotAllowed(t *testing.T) {
-	svc := NewService(1, &stubVerifier{})
+	svc := NewService(1, &stubVerifier{}, Options{})
 	rec := httptest.NewRecorder()
 	svc.HandleRevocationsBump(rec, httptest.NewRequest(http.MethodGet, "/revocations/bump", nil))
 	if rec.Code != http.StatusMethodNotAllowed {
@@ -129,7 +155,7 @@ func TestRevocationBumpMethodNotAllowed(t *testing.T) {
 }
 
 func TestRevocationsGet(t *testing.T) {
-	svc := NewService(9, &stubVerifier{})
+	svc := NewService(9, &stubVerifier{}, Options{})
 	rec := httptest.NewRecorder()
 	svc.HandleRevocationsGet(rec, httptest.NewRequest(http.MethodGet, "/revocations", nil))
 	if rec.Code != http.StatusOK {
@@ -145,7 +171,7 @@ func TestRevocationsGet(t *testing.T) {
 }
 
 func TestRevocationsGetMethodNotAllowed(t *testing.T) {
-	svc := NewService(1, &stubVerifier{})
+	svc := NewService(1, &stubVerifier{}, Options{})
 	rec := httptest.NewRecorder()
 	svc.HandleRevocationsGet(rec, httptest.NewRequest(http.MethodPost, "/revocations", nil))
 	if rec.Code != http.StatusMethodNotAllowed {
@@ -153,7 +179,8 @@ func TestRevocationsGetMethodNotAllowed(t *testing.T) {
 	}
 }
 
-func TestValidateWindowsErrors(t *testing.T) {
+func TestVerifyWindowErrors(t *testing.T) {
+	const rmt = "urn:lane2:token:RMT:EU:PSD3:3.2"
 	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
 	cases := []struct {
 		name     string
@@ -170,24 +197,1021 @@ func TestValidateWindowsErrors(t *testing.T) {
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			var tokens map[string]string
+			tokens := happyTokens()
+			delete(tokens, rmt)
 			if tc.payload != "" {
-				tokens = map[string]string{"urn:test": tc.payload}
-			}
-			err := validateWindows(now, &stubVerifier{tokens: tokens}, VerifyRequest{
-				Tokens: struct {
-					RMT  string `json:"rmt"`
-					IMT  string `json:"imt"`
-					CORT string `json:"cort"`
-					PSRT string `json:"psrt"`
-					AMLS string `json:"amls,omitempty"`
-					AMLV string `json:"amlv,omitempty"`
-				}{
-					RMT: "urn:test",
-				},
-			})
-			if err == nil || !strings.Contains(err.Error(), tc.expected) {
-				t.Fatalf("expected error containing %q, got %v", tc.expected, err)
+				tokens[rmt] = tc.payload
+			}
+			stub := &stubVerifier{tokens: tokens}
+			req := VerifyRequest{}
+			req.Tokens.RMT = rmt
+			req.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+			req.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+			req.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+			svc := NewService(1, stub, Options{Clock: FixedClock(now), Skew: -1})
+			res := svc.evaluate(context.Background(), svc.current(), req)
+			if res.valid || res.reason != tc.expected+":"+rmt {
+				t.Fatalf("expected %s:%s, got valid=%v reason=%q", tc.expected, rmt, res.valid, res.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyFailureProblemDetails(t *testing.T) {
+	cases := []struct {
+		name       string
+		mutate     func(map[string]string, *VerifyRequest)
+		wantStatus int
+		wantType   string
+	}{
+		{"missing", func(_ map[string]string, req *VerifyRequest) { req.Tokens.PSRT = "" }, http.StatusBadRequest, "missing_token"},
+		{"unknown", func(tokens map[string]string, _ *VerifyRequest) { delete(tokens, "urn:lane2:token:IMT:EU:SG:2025") }, http.StatusNotFound, "token_not_found"},
+		{"expired", func(tokens map[string]string, _ *VerifyRequest) {
+			tokens["urn:lane2:token:CORT:VODAFONE.VISA:2025"] = `{"exp":"2001-01-01T00:00:00Z"}`
+		}, http.StatusForbidden, "token_expired"},
+		{"malformed", func(tokens map[string]string, _ *VerifyRequest) {
+			tokens["urn:lane2:token:CORT:VODAFONE.VISA:2025"] = `{"exp":"soon"}`
+		}, http.StatusUnprocessableEntity, "token_malformed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := happyTokens()
+			req := VerifyRequest{}
+			req.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+			req.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+			req.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+			req.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+			tc.mutate(tokens, &req)
+			svc := NewService(3, &stubVerifier{tokens: tokens}, Options{})
+			body, _ := json.Marshal(req)
+			rec := httptest.NewRecorder()
+			svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected %d got %d", tc.wantStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Fatalf("unexpected content type %q", ct)
+			}
+			var out struct {
+				Type     string `json:"type"`
+				Valid    bool   `json:"valid"`
+				RevEpoch uint64 `json:"revEpoch"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if out.Type != "https://lane2.ai/ietf/imt-rmt/errors#"+tc.wantType || out.Valid || out.RevEpoch != 3 {
+				t.Fatalf("unexpected problem %+v", out)
+			}
+		})
+	}
+}
+
+func TestVerifyInvalidIMT(t *testing.T) {
+	svc := NewService(1, &stubVerifier{tokens: happyTokens(), imtErr: errors.New("malformed corridor")}, Options{})
+	payload := VerifyRequest{}
+	payload.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+	payload.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+	payload.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+	payload.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+	body, _ := json.Marshal(payload)
+	rec := httptest.NewRecorder()
+	svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+
+	var resp struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusForbidden || resp.Reason != "invalid_imt" || !strings.HasSuffix(resp.Type, "#token_type_invalid") {
+		t.Fatalf("expected invalid_imt problem, got %d %+v", rec.Code, resp)
+	}
+}
+
+func TestVerifyInvalidJSON(t *testing.T) {
+	svc := NewService(1, &stubVerifier{}, Options{})
+	rec := httptest.NewRecorder()
+	svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader("{")))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", rec.Code)
+	}
+}
+
+func TestReferenceFailures(t *testing.T) {
+	const (
+		rmtEU = "urn:lane2:token:RMT:EU:PSD3:3.2"
+		rmtSG = "urn:lane2:token:RMT:SG:PSD3:3.2"
+		rrmt  = "urn:lane2:token:RRMT:EU:PSD3:3.2"
+		imt   = "urn:lane2:token:IMT:EU:SG:2025"
+		cort  = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+	)
+	cortBound := `{"references":{"rmt":"` + rmtEU + `","rrmt":"` + rrmt + `"}}`
+	cases := []struct {
+		name     string
+		mutate   func(map[string]string, *VerifyRequest)
+		expected string
+		role     string
+	}{
+		{"consistent", func(map[string]string, *VerifyRequest) {}, "", ""},
+		{"rmtOutsideIMT", func(tokens map[string]string, req *VerifyRequest) {
+			tokens["urn:lane2:token:RMT:US:PSD3:1"] = `{}`
+			req.Tokens.RMT = "urn:lane2:token:RMT:US:PSD3:1"
+		}, "rmt_not_referenced:" + imt, "imt"},
+		{"cortBoundElsewhere", func(tokens map[string]string, req *VerifyRequest) { req.Tokens.RMT = rmtSG }, "rmt_not_referenced:" + cort, "cort"},
+		{"rrmtMismatch", func(tokens map[string]string, req *VerifyRequest) {
+			tokens["urn:lane2:token:RRMT:SG:1"] = `{}`
+			req.Tokens.RRMT = "urn:lane2:token:RRMT:SG:1"
+		}, "rrmt_not_referenced:" + cort, "cort"},
+		{"referenceMissing", func(tokens map[string]string, _ *VerifyRequest) { delete(tokens, rmtSG) }, "reference_missing:" + rmtSG, "imt"},
+		{"referenceRevoked", func(tokens map[string]string, _ *VerifyRequest) { tokens[rrmt] = `{"revoked":true}` }, "reference_revoked:" + rrmt, "cort"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := map[string]string{
+				rmtEU: `{}`,
+				rmtSG: `{}`,
+				rrmt:  `{}`,
+				imt:   `{"references":{"rmt_a":"` + rmtEU + `","rmt_b":"` + rmtSG + `"}}`,
+				cort:  cortBound,
+			}
+			req := VerifyRequest{}
+			req.Tokens.RMT = rmtEU
+			req.Tokens.IMT = imt
+			req.Tokens.CORT = cort
+			req.Tokens.RRMT = rrmt
+			tc.mutate(tokens, &req)
+			failures := referenceFailures(&stubVerifier{tokens: tokens}, req)
+			if tc.expected == "" {
+				if len(failures) > 0 {
+					t.Fatalf("unexpected failures %+v", failures)
+				}
+				return
+			}
+			if len(failures) == 0 || failures[0] != (roleFailure{tc.role, tc.expected}) {
+				t.Fatalf("expected %s failure %q, got %+v", tc.role, tc.expected, failures)
+			}
+		})
+	}
+}
+
+func TestValidateContext(t *testing.T) {
+	const (
+		rrmt = "urn:lane2:token:RRMT:EU:PSD3:3.2"
+		imt  = "urn:lane2:token:IMT:EU:SG:2025"
+		cort = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+		psrt = "urn:lane2:token:PSRT:VISA:ACQ-123"
+	)
+	tokens := map[string]string{
+		rrmt: `{"jurisdiction":["EU"],"domain":"payments_psd3","currency":"EUR","pricing":{"tiers":[{"min":0,"max":10000},{"min":10000,"max":50000}]}}`,
+		imt:  `{"corridor":"EU->SG","domains":["payments_psd3"]}`,
+		cort: `{"references":{"rrmt":"` + rrmt + `"},"parties":[{"id":"did:org:vodafone"},{"id":"did:org:visa"}]}`,
+		psrt: `{"acquirer":"did:org:visa","scheme":"VISA"}`,
+	}
+	match := ExecutionContext{Corridor: "eu-sg", Domain: "payments_psd3", Payee: "did:org:vodafone", Amount: "125.50", Currency: "eur", Scheme: "visa"}
+	cases := []struct {
+		name     string
+		mutate   func(*ExecutionContext)
+		expected string
+	}{
+		{"match", func(*ExecutionContext) {}, ""},
+		{"corridor", func(ec *ExecutionContext) { ec.Corridor = "EU-US" }, "context_mismatch:corridor"},
+		{"badCorridor", func(ec *ExecutionContext) { ec.Corridor = "EUR" }, "invalid_context:corridor"},
+		{"domain", func(ec *ExecutionContext) { ec.Domain = "lending" }, "context_mismatch:domain"},
+		{"payer", func(ec *ExecutionContext) { ec.Payer = "did:org:stranger" }, "context_mismatch:payer"},
+		{"payee", func(ec *ExecutionContext) { ec.Payee = "did:org:stranger" }, "context_mismatch:payee"},
+		{"currency", func(ec *ExecutionContext) { ec.Currency = "SGD" }, "context_mismatch:currency"},
+		{"scheme", func(ec *ExecutionContext) { ec.Scheme = "MASTERCARD" }, "context_mismatch:scheme"},
+		{"amountAboveTiers", func(ec *ExecutionContext) { ec.Amount = "50000.01" }, "context_mismatch:amount"},
+		{"badAmount", func(ec *ExecutionContext) { ec.Amount = "12,5" }, "invalid_context:amount"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec := match
+			tc.mutate(&ec)
+			req := VerifyRequest{Context: &ec}
+			req.Tokens.IMT = imt
+			req.Tokens.CORT = cort
+			req.Tokens.PSRT = psrt
+			err := validateContext(&stubVerifier{tokens: tokens}, req)
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expected {
+				t.Fatalf("expected %q, got %v", tc.expected, err)
+			}
+		})
+	}
+
+	mismatched := map[string]string{}
+	for uri, payload := range tokens {
+		mismatched[uri] = payload
+	}
+	mismatched[psrt] = `{"acquirer":"did:org:other"}`
+	req := VerifyRequest{Context: &ExecutionContext{}}
+	req.Tokens.IMT, req.Tokens.CORT, req.Tokens.PSRT = imt, cort, psrt
+	if err := validateContext(&stubVerifier{tokens: mismatched}, req); err == nil || err.Error() != "context_mismatch:acquirer" {
+		t.Fatalf("expected acquirer mismatch, got %v", err)
+	}
+
+	noParties := map[string]string{}
+	for uri, payload := range tokens {
+		noParties[uri] = payload
+	}
+	noParties[cort] = `{"references":{"rrmt":"` + rrmt + `"}}`
+	noParties[psrt] = `{"scheme":"VISA"}`
+	for field, ec := range map[string]ExecutionContext{
+		"payer": {Payer: "did:org:vodafone"},
+		"payee": {Payee: "did:org:vodafone"},
+	} {
+		req := VerifyRequest{Context: &ec}
+		req.Tokens.IMT, req.Tokens.CORT, req.Tokens.PSRT = imt, cort, psrt
+		if err := validateContext(&stubVerifier{tokens: noParties}, req); err == nil || err.Error() != "context_mismatch:"+field {
+			t.Fatalf("expected %s mismatch without CORT parties, got %v", field, err)
+		}
+	}
+	noParties[psrt] = `{"acquirer":"did:org:visa"}`
+	req = VerifyRequest{Context: &ExecutionContext{}}
+	req.Tokens.IMT, req.Tokens.CORT, req.Tokens.PSRT = imt, cort, psrt
+	if err := validateContext(&stubVerifier{tokens: noParties}, req); err == nil || err.Error() != "context_mismatch:acquirer" {
+		t.Fatalf("expected acquirer mismatch without CORT parties, got %v", err)
+	}
+}
+
+func TestVerifyAMLTokens(t *testing.T) {
+	const (
+		imt  = "urn:lane2:token:IMT:EU:SG:2025"
+		amls = "urn:lane2:token:AMLS:ACME:42"
+		amlv = "urn:lane2:token:AMLV:ACME:42"
+	)
+	window := `"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z"`
+	cases := []struct {
+		name     string
+		mutate   func(map[string]string, *VerifyRequest, *stubVerifier)
+		expected string
+	}{
+		{"optionalAbsent", func(map[string]string, *VerifyRequest, *stubVerifier) {}, ""},
+		{"present", func(tokens map[string]string, req *VerifyRequest, _ *stubVerifier) {
+			req.Tokens.AMLS, req.Tokens.AMLV = amls, amlv
+		}, ""},
+		{"revoked", func(tokens map[string]string, req *VerifyRequest, _ *stubVerifier) {
+			tokens[amls] = `{"revoked":true}`
+			req.Tokens.AMLS = amls
+		}, "token_revoked:" + amls},
+		{"unknown", func(tokens map[string]string, req *VerifyRequest, _ *stubVerifier) {
+			req.Tokens.AMLV = "urn:lane2:token:AMLV:NOPE"
+		}, "metadata_missing:urn:lane2:token:AMLV:NOPE"},
+		{"wrongType", func(tokens map[string]string, req *VerifyRequest, stub *stubVerifier) {
+			req.Tokens.AMLV = amlv
+			stub.amlvErr = errors.New("unexpected type")
+		}, "invalid_amlv"},
+		{"requiredByCorridor", func(tokens map[string]string, req *VerifyRequest, _ *stubVerifier) {
+			tokens[imt] = `{` + window + `,"required_tokens":["RMT","IMT","CORT","PSRT","AMLS"],"references":{"rmt_a":"urn:lane2:token:RMT:EU:PSD3:3.2","rmt_b":"urn:lane2:token:RMT:SG:PSD3:3.2"}}`
+		}, "missing_amls"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := happyTokens()
+			tokens[amls] = `{` + window + `}`
+			tokens[amlv] = `{` + window + `}`
+			req := VerifyRequest{}
+			req.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+			req.Tokens.IMT = imt
+			req.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+			req.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+			stub := &stubVerifier{tokens: tokens}
+			tc.mutate(tokens, &req, stub)
+			svc := NewService(1, stub, Options{})
+			res := svc.evaluate(context.Background(), svc.current(), req)
+			if tc.expected == "" {
+				if !res.valid {
+					t.Fatalf("unexpected failure %s", res.reason)
+				}
+				return
+			}
+			if res.valid || res.reason != tc.expected {
+				t.Fatalf("expected %q, got valid=%v reason=%q", tc.expected, res.valid, res.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyReportIsDeterministic(t *testing.T) {
+	tokens := happyTokens()
+	tokens["urn:lane2:token:CORT:VODAFONE.VISA:2025"] = `{"nbf":"2000-01-01T00:00:00Z","exp":"2001-01-01T00:00:00Z"}`
+	tokens["urn:lane2:token:PSRT:VISA:ACQ-123"] = `{"revoked":true}`
+	payload := VerifyRequest{}
+	payload.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+	payload.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+	payload.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+	payload.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+	body, _ := json.Marshal(payload)
+
+	var first string
+	for i := 0; i < 20; i++ {
+		svc := NewService(1, &stubVerifier{tokens: tokens}, Options{})
+		rec := httptest.NewRecorder()
+		svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+		if i == 0 {
+			first = rec.Body.String()
+			continue
+		}
+		if rec.Body.String() != first {
+			t.Fatalf("expected identical reports, got\n%s\n%s", first, rec.Body.String())
+		}
+	}
+
+	var out struct {
+		Valid  bool         `json:"valid"`
+		Reason string       `json:"reason"`
+		Checks []TokenCheck `json:"checks"`
+	}
+	if err := json.Unmarshal([]byte(first), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Valid || out.Reason != "token_expired:urn:lane2:token:CORT:VODAFONE.VISA:2025" {
+		t.Fatalf("expected the first failure in role order, got %+v", out)
+	}
+	var roles []string
+	for _, check := range out.Checks {
+		roles = append(roles, check.Role)
+	}
+	if strings.Join(roles, ",") != "rmt,imt,cort,psrt" {
+		t.Fatalf("unexpected roles %v", roles)
+	}
+	rmt, cort, psrt := out.Checks[0], out.Checks[2], out.Checks[3]
+	if rmt.Status != CheckPass || rmt.Window != CheckPass || rmt.TypeCheck != CheckPass || !strings.HasPrefix(rmt.Hash, "sha256:") {
+		t.Fatalf("unexpected rmt check %+v", rmt)
+	}
+	if cort.Status != CheckFail || cort.Window != CheckFail || cort.Revocation != CheckPass || cort.References != CheckPass {
+		t.Fatalf("unexpected cort check %+v", cort)
+	}
+	if psrt.Status != CheckFail || psrt.Revocation != CheckFail || psrt.Reason != "token_revoked:urn:lane2:token:PSRT:VISA:ACQ-123" {
+		t.Fatalf("unexpected psrt check %+v", psrt)
+	}
+}
+
+func TestVerifyReportOnSuccess(t *testing.T) {
+	svc := NewService(1, &stubVerifier{tokens: happyTokens()}, Options{})
+	payload := VerifyRequest{}
+	payload.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+	payload.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+	payload.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+	payload.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+	body, _ := json.Marshal(payload)
+	rec := httptest.NewRecorder()
+	svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+
+	var resp VerifyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !resp.Valid || len(resp.Checks) != 4 {
+		t.Fatalf("unexpected resp %+v", resp)
+	}
+	for _, check := range resp.Checks {
+		if check.Status != CheckPass || check.Signature != CheckSkipped {
+			t.Fatalf("unexpected check %+v", check)
+		}
+	}
+	if resp.Checks[1].References != CheckPass || resp.Checks[0].References != CheckSkipped {
+		t.Fatalf("expected references reported on the IMT only, got %+v", resp.Checks[:2])
+	}
+}
+
+func TestVerifyClockAndSkew(t *testing.T) {
+	const cort = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+	expiry := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		now    time.Time
+		skew   time.Duration
+		reason string
+	}{
+		{"withinDefaultSkew", expiry.Add(119 * time.Second), 0, ""},
+		{"beyondDefaultSkew", expiry.Add(121 * time.Second), 0, "token_expired:" + cort},
+		{"skewDisabled", expiry.Add(time.Second), -1, "token_expired:" + cort},
+		{"widerSkew", expiry.Add(5 * time.Minute), 10 * time.Minute, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := happyTokens()
+			tokens[cort] = `{"nbf":"2000-01-01T00:00:00Z","exp":"2025-06-01T00:00:00Z"}`
+			stub := &stubVerifier{tokens: tokens}
+			svc := NewService(1, stub, Options{Clock: FixedClock(tc.now), Skew: tc.skew})
+			req := VerifyRequest{}
+			req.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+			req.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+			req.Tokens.CORT = cort
+			req.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+			if res := svc.evaluate(context.Background(), svc.current(), req); res.reason != tc.reason {
+				t.Fatalf("expected reason %q, got %q", tc.reason, res.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyHistoricalAt(t *testing.T) {
+	tokens := happyTokens()
+	tokens["urn:lane2:token:CORT:VODAFONE.VISA:2025"] = `{"nbf":"2000-01-01T00:00:00Z","exp":"2001-01-01T00:00:00Z"}`
+	req := VerifyRequest{At: "2000-06-01T00:00:00Z"}
+	req.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+	req.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+	req.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+	req.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+	body, _ := json.Marshal(req)
+
+	rec := httptest.NewRecorder()
+	NewService(1, &stubVerifier{tokens: tokens}, Options{}).HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "historical_verification_disabled") {
+		t.Fatalf("expected historical verification to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	historical := NewService(1, &stubVerifier{tokens: tokens}, Options{AllowHistorical: true})
+	rec = httptest.NewRecorder()
+	historical.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+	var resp VerifyResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || !resp.Valid || resp.At != req.At {
+		t.Fatalf("expected tuple valid at %s, got %d %+v", req.At, rec.Code, resp)
+	}
+
+	req.At = "last year"
+	body, _ = json.Marshal(req)
+	rec = httptest.NewRecorder()
+	historical.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_at") {
+		t.Fatalf("expected invalid_at, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	tokens := happyTokens()
+	tokens["urn:lane2:token:PSRT:VISA:ACQ-999"] = `{"nbf":"2000-01-01T00:00:00Z","exp":"2001-01-01T00:00:00Z","revoked":false}`
+	svc := NewService(7, &stubVerifier{tokens: tokens}, Options{BatchWorkers: 2})
+
+	valid := VerifyRequest{}
+	valid.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+	valid.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+	valid.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+	valid.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+	missing := valid
+	missing.Tokens.RMT = ""
+	expired := valid
+	expired.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-999"
+
+	body, _ := json.Marshal([]VerifyRequest{valid, missing, expired, valid})
+	rec := httptest.NewRecorder()
+	svc.HandleVerifyBatch(rec, httptest.NewRequest(http.MethodPost, "/verify/batch", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal resp: %v", err)
+	}
+	if resp.RevEpoch != 7 || len(resp.Results) != 4 {
+		t.Fatalf("unexpected batch: %+v", resp)
+	}
+	want := []string{"", "missing_rmt", "token_expired:urn:lane2:token:PSRT:VISA:ACQ-999", ""}
+	for i, result := range resp.Results {
+		if result.Valid != (want[i] == "") || result.Reason != want[i] || result.RevEpoch != 7 {
+			t.Fatalf("result %d: expected %q got %+v", i, want[i], result)
+		}
+	}
+}
+
+func TestVerifyBatchRejectsMalformedBatch(t *testing.T) {
+	svc := NewService(1, &stubVerifier{tokens: happyTokens()}, Options{})
+	tooMany, _ := json.Marshal(make([]VerifyRequest, MaxBatchSize+1))
+	cases := map[string]string{
+		"object":   `{"tokens":{}}`,
+		"empty":    `[]`,
+		"too many": string(tooMany),
+	}
+	for name, body := range cases {
+		rec := httptest.NewRecorder()
+		svc.HandleVerifyBatch(rec, httptest.NewRequest(http.MethodPost, "/verify/batch", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 got %d", name, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	svc.HandleVerifyBatch(rec, httptest.NewRequest(http.MethodGet, "/verify/batch", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", rec.Code)
+	}
+}
+
+func signInline(priv ed25519.PrivateKey, kid, payload string) string {
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","kid":"` + kid + `"}`))
+	body := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	sig := ed25519.Sign(priv, []byte(protected+"."+body))
+	return protected + "." + body + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyInlineTokens(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed([]byte("rtgf-registry-inline-test-seed-1"))
+	partner := ed25519.NewKeyFromSeed([]byte("rtgf-registry-inline-test-seed-2"))
+	rogue := ed25519.NewKeyFromSeed([]byte("rtgf-registry-inline-test-seed-3"))
+	opts := Options{
+		RegistryKeys: verifylib.KeySet{"registry": priv.Public().(ed25519.PublicKey)},
+		TrustedKeys:  verifylib.KeySet{"partner": partner.Public().(ed25519.PublicKey)},
+	}
+	window := `"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false`
+	rmtURI := "urn:lane2:token:RMT:EU:PSD3:3.2"
+	rmtPayload := `{"type":"RMT","rmt_id":"` + rmtURI + `","jurisdiction":"EU",` + mandateClaims + `,` + window + `}`
+	rmt := signInline(partner, "partner", rmtPayload)
+	psrt := signInline(priv, "registry", `{"type":"PSRT",`+psrtClaims+`,`+window+`}`)
+	expired := signInline(priv, "registry", `{"type":"PSRT",`+psrtClaims+`,"nbf":"2000-01-01T00:00:00Z","exp":"2001-01-01T00:00:00Z","revoked":false}`)
+	forged := signInline(rogue, "partner", `{"type":"PSRT",`+psrtClaims+`,`+window+`}`)
+	invalid := signInline(priv, "registry", `{"type":"PSRT","acquirer":"did:org:visa","capture":{"mode":"later"},`+window+`}`)
+
+	parts := strings.Split(psrt, ".")
+	jsonPSRT := `{"protected":"` + parts[0] + `","payload":"` + parts[1] + `","signature":"` + parts[2] + `"}`
+
+	request := func(rmt, psrt string) string {
+		return `{"tokens":{"rmt":` + rmt + `,"imt":"urn:lane2:token:IMT:EU:SG:2025","cort":"urn:lane2:token:CORT:VODAFONE.VISA:2025","psrt":` + psrt + `}}`
+	}
+	quote := func(s string) string { return `"` + s + `"` }
+	revoked := strings.Replace(rmtPayload, `"revoked":false`, `"revoked":true`, 1)
+	cases := []struct {
+		name   string
+		body   string
+		reason string
+		// indexed replaces the registry copy of the RMT.
+		indexed string
+	}{
+		{"compact", request(quote(rmt), quote(psrt)), "", ""},
+		{"json serialization", request(quote(rmt), jsonPSRT), "", ""},
+		{"expired", request(quote(rmt), quote(expired)), "token_expired:urn:lane2:inline:psrt", ""},
+		{"forged", request(quote(rmt), quote(forged)), "signature_invalid:psrt", ""},
+		{"malformed", request(quote(rmt), quote("a.b.c")), "inline_malformed:psrt", ""},
+		{"schema", request(quote(rmt), quote(invalid)), "schema_invalid:urn:lane2:inline:psrt", ""},
+		{"missing wins", request(`""`, quote(forged)), "missing_rmt", ""},
+		{"revoked in registry", request(quote(rmt), quote(psrt)), "token_revoked:" + rmtURI, revoked},
+		{"differs from registry", request(quote(rmt), quote(psrt)), "inline_mismatch:" + rmtURI, strings.Replace(rmtPayload, `"EU"`, `"SG"`, 1)},
+	}
+	for _, tc := range cases {
+		tokens := happyTokens()
+		tokens[rmtURI] = rmtPayload
+		if tc.indexed != "" {
+			tokens[rmtURI] = tc.indexed
+		}
+		svc := NewService(1, &stubVerifier{tokens: tokens}, opts)
+		rec := httptest.NewRecorder()
+		svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(tc.body)))
+		var resp VerifyResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: unmarshal resp: %v", tc.name, err)
+		}
+		if resp.Reason != tc.reason || resp.Valid != (tc.reason == "") {
+			t.Fatalf("%s: expected %q got %+v", tc.name, tc.reason, resp)
+		}
+		if tc.reason == "" {
+			if got := resp.Checks[0]; got.URI != "urn:lane2:token:RMT:EU:PSD3:3.2" || got.Signature != CheckPass || got.TypeCheck != CheckPass {
+				t.Fatalf("%s: unexpected rmt check %+v", tc.name, got)
+			}
+			if got := resp.Checks[1]; got.Signature != CheckSkipped {
+				t.Fatalf("%s: expected indexed imt signature skipped, got %+v", tc.name, got)
+			}
+		}
+		if tc.name == "revoked in registry" {
+			if got := resp.Checks[0]; got.Signature != CheckPass || got.Revocation != CheckFail {
+				t.Fatalf("revoked: unexpected rmt check %+v", got)
+			}
+		}
+		if tc.name == "forged" {
+			if got := resp.Checks[3]; got.Signature != CheckFail || got.URI != "" {
+				t.Fatalf("forged: unexpected psrt check %+v", got)
+			}
+			if !strings.Contains(rec.Body.String(), "https://lane2.ai/ietf/imt-rmt/errors#token_signature_invalid") || rec.Code != http.StatusForbidden {
+				t.Fatalf("forged: expected token_signature_invalid 403, got %d %s", rec.Code, rec.Body.String())
+			}
+		}
+		if tc.name == "schema" {
+			want := []verifylib.SchemaViolation{{Pointer: "/scheme", Message: "is required"}, {Pointer: "/capture/mode", Message: `must be one of ["auto","manual"]`}}
+			if got := resp.Checks[3]; got.Signature != CheckPass || !reflect.DeepEqual(got.Violations, want) {
+				t.Fatalf("schema: unexpected psrt check %+v", got)
+			}
+			if !strings.Contains(rec.Body.String(), "https://lane2.ai/ietf/imt-rmt/errors#token_malformed") {
+				t.Fatalf("schema: expected token_malformed problem, got %s", rec.Body.String())
+			}
+		}
+	}
+}
+
+// Schema-required claims shared by RMT and IMT payloads, and minimal PSRT
+// claims.
+const (
+	mandateClaims = `"domain":"payments_psd3","effective_date":"2025-10-01T00:00:00Z","expires_at":"2026-10-01T00:00:00Z","policy_snapshot_hash":"sha256:test"`
+	psrtClaims    = `"acquirer":"did:org:visa","scheme":"VISA","capture":{"mode":"auto"}`
+)
+
+const cortTerms = `"parties":[{"id":"did:org:vodafone","role":"merchant_of_record"},{"id":"did:org:visa","role":"scheme"}],` +
+	`"splits":[{"party":"did:org:vodafone","pct":0.95},{"party":"did:org:visa","pct":0.05}]`
+
+func TestVerifyInlineWithoutRegistry(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed([]byte("rtgf-registry-inline-test-seed-1"))
+	svc := NewService(1, nil, Options{RegistryKeys: verifylib.KeySet{"registry": priv.Public().(ed25519.PublicKey)}})
+	window := `"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false`
+	rmtA := `"urn:lane2:token:RMT:EU:PSD3:3.2"`
+	payload := VerifyRequest{}
+	payload.Tokens.RMT = signInline(priv, "registry", `{"type":"RMT","rmt_id":`+rmtA+`,"jurisdiction":"EU",`+mandateClaims+`,`+window+`}`)
+	payload.Tokens.IMT = signInline(priv, "registry", `{"type":"IMT","uri":"urn:lane2:token:IMT:EU:SG:2025","imt_id":"urn:lane2:token:IMT:EU:SG:2025",`+mandateClaims+`,"corridor":"EU-SG","references":{"rmt_a":`+rmtA+`,"rmt_b":"urn:lane2:token:RMT:SG:PSD3:3.2"},`+window+`}`)
+	payload.Tokens.CORT = signInline(priv, "registry", `{"type":"CORT","references":{"rmt":`+rmtA+`},`+cortTerms+`,`+window+`}`)
+	payload.Tokens.PSRT = signInline(priv, "registry", `{"type":"PSRT",`+psrtClaims+`,`+window+`}`)
+	body, _ := json.Marshal(payload)
+	rec := httptest.NewRecorder()
+	svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+	var resp VerifyResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Valid || resp.Reason != "reference_missing:urn:lane2:token:RMT:SG:PSD3:3.2" {
+		t.Fatalf("expected the unsubmitted rmt_b to be missing, got %+v", resp)
+	}
+	for _, check := range resp.Checks {
+		if check.Signature != CheckPass || check.Window != CheckPass || check.TypeCheck != CheckPass {
+			t.Fatalf("unexpected check %+v", check)
+		}
+	}
+}
+
+func TestVerifyReplayCache(t *testing.T) {
+	const psrt = "urn:lane2:token:PSRT:VISA:ACQ-123"
+	tokens := happyTokens()
+	stub := &stubVerifier{tokens: tokens}
+	svc := NewService(1, stub, Options{Clock: FixedClock(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC))})
+	verify := func() VerifyResponse {
+		payload := VerifyRequest{}
+		payload.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+		payload.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+		payload.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+		payload.Tokens.PSRT = psrt
+		body, _ := json.Marshal(payload)
+		rec := httptest.NewRecorder()
+		svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+		var resp VerifyResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	tokens[psrt] = `{"iss":"did:web:registry","jti":"psrt-1","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`
+	if resp := verify(); !resp.Valid || resp.Checks[3].Replay != CheckPass || resp.Checks[0].Replay != CheckSkipped {
+		t.Fatalf("expected first observation to pass, got %+v", resp)
+	}
+	if resp := verify(); !resp.Valid {
+		t.Fatalf("expected an identical replay to pass, got %+v", resp)
+	}
+	tokens[psrt] = `{"iss":"did:web:registry","jti":"psrt-1","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false,"acquirer":"other"}`
+	if resp := verify(); resp.Reason != "jti_conflict:"+psrt || resp.Checks[3].Replay != CheckFail {
+		t.Fatalf("expected jti_conflict, got %+v", resp)
+	}
+
+	tokens[psrt] = `{"iss":"did:web:registry","jti":"psrt-2","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":true}`
+	if resp := verify(); resp.Reason != "token_revoked:"+psrt {
+		t.Fatalf("expected token_revoked, got %+v", resp)
+	}
+	tokens[psrt] = `{"iss":"did:web:registry","jti":"psrt-2","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`
+	if resp := verify(); resp.Reason != "jti_revoked:"+psrt {
+		t.Fatalf("expected jti_revoked for a stale unrevoked copy, got %+v", resp)
+	}
+}
+
+func TestVerifyReceipt(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed([]byte("rtgf-registry-receipt-test-seed1"))
+	signer, err := crypto.NewSigner("registry", priv)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	keys := verifylib.KeySet{"registry": priv.Public().(ed25519.PublicKey)}
+	tokens := happyTokens()
+	tokens["urn:lane2:token:PSRT:VISA:ACQ-999"] = `{"nbf":"2000-01-01T00:00:00Z","exp":"2001-01-01T00:00:00Z","revoked":false}`
+	svc := NewService(5, &stubVerifier{tokens: tokens}, Options{
+		Clock:  FixedClock(time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)),
+		Signer: signer,
+		Issuer: "did:web:registry.test",
+	})
+	verify := func(psrt string) (VerifyResponse, Receipt) {
+		t.Helper()
+		payload := VerifyRequest{}
+		payload.Tokens.RMT = "urn:lane2:token:RMT:EU:PSD3:3.2"
+		payload.Tokens.IMT = "urn:lane2:token:IMT:EU:SG:2025"
+		payload.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+		payload.Tokens.PSRT = psrt
+		body, _ := json.Marshal(payload)
+		rec := httptest.NewRecorder()
+		svc.HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+		var resp VerifyResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal resp: %v", err)
+		}
+		claims, err := verifylib.VerifyJWS([]byte(resp.Receipt), keys)
+		if err != nil {
+			t.Fatalf("receipt does not verify: %v (%s)", err, rec.Body.String())
+		}
+		var receipt Receipt
+		if err := json.Unmarshal(claims, &receipt); err != nil {
+			t.Fatalf("decode receipt: %v", err)
+		}
+		return resp, receipt
+	}
+
+	resp, receipt := verify("urn:lane2:token:PSRT:VISA:ACQ-123")
+	if !resp.Valid || !receipt.Valid || receipt.RevEpoch != 5 || receipt.Issuer != "did:web:registry.test" ||
+		receipt.At != "2025-10-01T12:00:00Z" || receipt.IssuedAt != 1759320000 || len(receipt.Reasons) != 0 {
+		t.Fatalf("unexpected receipt %+v", receipt)
+	}
+	if len(receipt.Tokens) != 4 || receipt.Tokens[3].Hash != resp.Checks[3].Hash || receipt.Tokens[3].URI != "urn:lane2:token:PSRT:VISA:ACQ-123" {
+		t.Fatalf("unexpected receipt tokens %+v", receipt.Tokens)
+	}
+	_, again := verify("urn:lane2:token:PSRT:VISA:ACQ-123")
+	if again.RequestHash != receipt.RequestHash || !strings.HasPrefix(receipt.RequestHash, "sha256:") {
+		t.Fatalf("expected a stable request hash, got %s and %s", receipt.RequestHash, again.RequestHash)
+	}
+
+	resp, failed := verify("urn:lane2:token:PSRT:VISA:ACQ-999")
+	if resp.Valid || failed.Valid || failed.RequestHash == receipt.RequestHash ||
+		len(failed.Reasons) != 1 || failed.Reasons[0] != "token_expired:urn:lane2:token:PSRT:VISA:ACQ-999" {
+		t.Fatalf("unexpected failure receipt %+v", failed)
+	}
+}
+
+// quoteRRMT mirrors the bundled rrmt-eu-psd3-2025.json pricing.
+const quoteRRMT = `{"type":"RRMT","version":"2025.10","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false,` +
+	`"domain":"payments_psd3","jurisdiction":["EU"],"currency":"EUR","pricing":{"mode":"tiered","tiers":[` +
+	`{"min":0,"max":10000,"fee_pct":0.45,"min_fee":0.1},{"min":10000,"max":50000,"fee_pct":0.35},{"min":50000,"max":1000000,"fee_pct":0.25}],` +
+	`"surcharges":[{"code":"cross_border","pct":0.1},{"code":"high_risk_mcc","pct":0.2}]},"tax":{"vat_pct":%s}}`
+
+func TestQuote(t *testing.T) {
+	const (
+		rrmt    = "urn:lane2:token:RRMT:EU:PSD3:3.2"
+		rrmtVAT = "urn:lane2:token:RRMT:EU:PSD3:VAT"
+		expired = "urn:lane2:token:RRMT:EU:PSD3:OLD"
+	)
+	tokens := happyTokens()
+	tokens[rrmt] = fmt.Sprintf(quoteRRMT, "0")
+	tokens[rrmtVAT] = fmt.Sprintf(quoteRRMT, "20")
+	tokens[expired] = strings.Replace(tokens[rrmt], "2100-01-01", "2001-01-01", 1)
+	svc := NewService(3, &stubVerifier{tokens: tokens}, Options{})
+	quote := func(body string) (*httptest.ResponseRecorder, Quote) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		svc.HandleQuote(rec, httptest.NewRequest(http.MethodPost, "/quote", strings.NewReader(body)))
+		var q Quote
+		_ = json.Unmarshal(rec.Body.Bytes(), &q)
+		return rec, q
+	}
+	lines := func(q Quote) []string {
+		var out []string
+		for _, line := range q.Breakdown {
+			out = append(out, line.Item+"="+line.Amount+line.Note)
+		}
+		return out
+	}
+
+	rec, q := quote(`{"rrmt":"` + rrmt + `","amount":"125.50","currency":"eur","flags":["cross_border"]}`)
+	hash, _ := verifylib.CanonicalDigest([]byte(tokens[rrmt]))
+	if rec.Code != http.StatusOK || q.Hash != hash || q.Version != "2025.10" || q.RevEpoch != 3 || q.Currency != "EUR" || q.Tier.Index != 0 {
+		t.Fatalf("unexpected quote %d %s", rec.Code, rec.Body.String())
+	}
+	if q.Fee != "0.56" || q.Surcharges != "0.13" || q.VAT != "0.00" || q.Total != "0.69" ||
+		!reflect.DeepEqual(lines(q), []string{"fee=0.56", "surcharge:cross_border=0.13", "vat=0.00"}) {
+		t.Fatalf("unexpected breakdown %s", rec.Body.String())
+	}
+
+	_, q = quote(`{"rrmt":"` + rrmt + `","amount":"12.50","flags":["high_risk_mcc","high_risk_mcc"]}`)
+	if !reflect.DeepEqual(lines(q), []string{"fee=0.10min_fee", "surcharge:high_risk_mcc=0.03", "vat=0.00"}) || q.Total != "0.13" {
+		t.Fatalf("expected min_fee floor and a half-up surcharge, got %+v", q)
+	}
+
+	_, q = quote(`{"rrmt":"` + rrmtVAT + `","amount":"20000","flags":["high_risk_mcc"]}`)
+	if q.Tier.Index != 1 || q.Amount != "20000.00" || q.Fee != "70.00" || q.Surcharges != "40.00" || q.VAT != "22.00" || q.Total != "132.00" {
+		t.Fatalf("unexpected tier 2 quote %+v", q)
+	}
+
+	failures := map[string]struct {
+		body   string
+		status int
+		reason string
+	}{
+		"currency":  {`{"rrmt":"` + rrmt + `","amount":"1","currency":"USD"}`, http.StatusForbidden, "context_mismatch:currency"},
+		"precision": {`{"rrmt":"` + rrmt + `","amount":"1.234"}`, http.StatusBadRequest, "invalid_quote:amount"},
+		"negative":  {`{"rrmt":"` + rrmt + `","amount":"-1"}`, http.StatusBadRequest, "invalid_quote:amount"},
+		"tiers":     {`{"rrmt":"` + rrmt + `","amount":"2000000"}`, http.StatusForbidden, "context_mismatch:amount"},
+		"flag":      {`{"rrmt":"` + rrmt + `","amount":"1","flags":["weekend"]}`, http.StatusBadRequest, "unknown_surcharge:weekend"},
+		"missing":   {`{"amount":"1"}`, http.StatusBadRequest, "missing_rrmt"},
+		"expired":   {`{"rrmt":"` + expired + `","amount":"1"}`, http.StatusForbidden, "token_expired:" + expired},
+	}
+	for name, tc := range failures {
+		rec, _ := quote(tc.body)
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != tc.status || body.Reason != tc.reason {
+			t.Fatalf("%s: expected %d %s, got %d %s", name, tc.status, tc.reason, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	svc.HandleQuote(rec, httptest.NewRequest(http.MethodGet, "/quote", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestSettlementPlan(t *testing.T) {
+	const (
+		cort     = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+		psrt     = "urn:lane2:token:PSRT:VISA:ACQ-123"
+		evenCORT = "urn:lane2:token:CORT:EVEN"
+		window   = `"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false`
+	)
+	tokens := happyTokens()
+	tokens["urn:lane2:token:RRMT:EU:PSD3:3.2"] = fmt.Sprintf(quoteRRMT, "0")
+	tokens[cort] = `{"type":"CORT",` + window + `,"parties":[{"id":"did:org:vodafone","role":"merchant_of_record"},` +
+		`{"id":"did:org:visa","role":"scheme"},{"id":"did:org:brokerXYZ","role":"allocation_agent"}],` +
+		`"references":{"rrmt":"urn:lane2:token:RRMT:EU:PSD3:3.2"},` +
+		`"splits":[{"party":"did:org:vodafone","pct":0.8},{"party":"did:org:brokerXYZ","pct":0.15},{"party":"did:org:visa","pct":0.05}],` +
+		`"payout":{"mode":"t+1","scheme":"SEPA_INSTANT"}}`
+	tokens[evenCORT] = `{"type":"CORT",` + window + `,"parties":[{"id":"did:org:a","role":"merchant_of_record"},{"id":"did:org:visa","role":"scheme"}],` +
+		`"references":{},"splits":[{"party":"did:org:a","pct":0.5},{"party":"did:org:visa","pct":0.5}]}`
+	tokens[psrt] = `{"type":"PSRT",` + window + `,"acquirer":"did:org:visa","scheme":"VISA","capture":{"mode":"auto","window_sec":86400},"dispute":{"reserve_pct":0.05}}`
+	tokens["urn:lane2:token:RRMT:REVOKED"] = strings.Replace(fmt.Sprintf(quoteRRMT, "0"), `"revoked":false`, `"revoked":true`, 1)
+	tokens["urn:lane2:token:CORT:STALE"] = strings.Replace(tokens[evenCORT], `"references":{}`, `"references":{"rrmt":"urn:lane2:token:RRMT:REVOKED"}`, 1)
+	tokens["urn:lane2:token:PSRT:OTHER"] = `{"type":"PSRT",` + window + `,"acquirer":"did:org:other","capture":{"mode":"auto"}}`
+	tokens["urn:lane2:token:PSRT:GREEDY"] = `{"type":"PSRT",` + window + `,"acquirer":"did:org:visa","capture":{"mode":"manual"},"dispute":{"reserve_pct":1.5}}`
+	svc := NewService(9, &stubVerifier{tokens: tokens}, Options{Clock: FixedClock(time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC))})
+	settle := func(body string) (*httptest.ResponseRecorder, SettlementPlan) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		svc.HandleSettlement(rec, httptest.NewRequest(http.MethodPost, "/settlement", strings.NewReader(body)))
+		var plan SettlementPlan
+		_ = json.Unmarshal(rec.Body.Bytes(), &plan)
+		return rec, plan
+	}
+	shares := func(plan SettlementPlan) []string {
+		var out []string
+		for _, share := range plan.Shares {
+			out = append(out, share.Party+"="+share.Amount)
+		}
+		return out
+	}
+
+	rec, plan := settle(`{"cort":"` + cort + `","psrt":"` + psrt + `","amount":"100.01","authorized_at":"2025-10-01T10:00:00+02:00"}`)
+	if rec.Code != http.StatusOK || plan.Currency != "EUR" || plan.RevEpoch != 9 || !strings.HasPrefix(plan.CORTHash, "sha256:") {
+		t.Fatalf("unexpected plan %d %s", rec.Code, rec.Body.String())
+	}
+	if plan.Reserve != (SettlementReserve{Holder: "did:org:visa", Pct: "0.05", Amount: "5.00"}) || plan.Distributable != "95.01" ||
+		!reflect.DeepEqual(shares(plan), []string{"did:org:vodafone=76.01", "did:org:brokerXYZ=14.25", "did:org:visa=4.75"}) {
+		t.Fatalf("expected the leftover cent on the largest remainder, got %s", rec.Body.String())
+	}
+	if plan.Shares[0].Role != "merchant_of_record" || plan.Payout != (SettlementPayout{Mode: "t+1", Scheme: "SEPA_INSTANT"}) {
+		t.Fatalf("unexpected roles or payout %s", rec.Body.String())
+	}
+	if plan.Capture.Mode != "auto" || plan.Capture.WindowSec == nil || *plan.Capture.WindowSec != 86400 ||
+		plan.Capture.AuthorizedAt != "2025-10-01T08:00:00Z" || plan.Capture.Deadline != "2025-10-02T08:00:00Z" {
+		t.Fatalf("unexpected capture %+v", plan.Capture)
+	}
+
+	_, plan = settle(`{"cort":"` + cort + `","psrt":"` + psrt + `","amount":"0.03"}`)
+	if plan.Reserve.Amount != "0.00" || !reflect.DeepEqual(shares(plan), []string{"did:org:vodafone=0.02", "did:org:brokerXYZ=0.01", "did:org:visa=0.00"}) ||
+		plan.Capture.AuthorizedAt != "2025-10-01T12:00:00Z" {
+		t.Fatalf("unexpected small plan %+v", plan)
+	}
+	_, plan = settle(`{"cort":"` + evenCORT + `","psrt":"` + psrt + `","amount":"0.21","currency":"EUR"}`)
+	if plan.Reserve.Amount != "0.01" || !reflect.DeepEqual(shares(plan), []string{"did:org:a=0.10", "did:org:visa=0.10"}) {
+		t.Fatalf("unexpected even plan %+v", plan)
+	}
+	_, plan = settle(`{"cort":"` + evenCORT + `","psrt":"` + psrt + `","amount":"0.22","currency":"EUR"}`)
+	if !reflect.DeepEqual(shares(plan), []string{"did:org:a=0.11", "did:org:visa=0.10"}) {
+		t.Fatalf("expected ties to go to the earlier split, got %+v", plan)
+	}
+
+	failures := map[string]struct {
+		body   string
+		status int
+		reason string
+	}{
+		"acquirer":   {`{"cort":"` + cort + `","psrt":"urn:lane2:token:PSRT:OTHER","amount":"1"}`, http.StatusForbidden, "context_mismatch:acquirer"},
+		"currency":   {`{"cort":"` + cort + `","psrt":"` + psrt + `","amount":"1","currency":"USD"}`, http.StatusForbidden, "context_mismatch:currency"},
+		"noCurrency": {`{"cort":"` + evenCORT + `","psrt":"` + psrt + `","amount":"1"}`, http.StatusBadRequest, "invalid_settlement:currency"},
+		"precision":  {`{"cort":"` + cort + `","psrt":"` + psrt + `","amount":"1.001"}`, http.StatusBadRequest, "invalid_settlement:amount"},
+		"authorized": {`{"cort":"` + cort + `","psrt":"` + psrt + `","amount":"1","authorized_at":"today"}`, http.StatusBadRequest, "invalid_settlement:authorized_at"},
+		"reserve":    {`{"cort":"` + cort + `","psrt":"urn:lane2:token:PSRT:GREEDY","amount":"1"}`, http.StatusUnprocessableEntity, "reserve_invalid:urn:lane2:token:PSRT:GREEDY"},
+		"missing":    {`{"cort":"` + cort + `","amount":"1"}`, http.StatusBadRequest, "missing_psrt"},
+		"rrmtRevoked": {`{"cort":"urn:lane2:token:CORT:STALE","psrt":"` + psrt + `","amount":"1"}`, http.StatusForbidden,
+			"token_revoked:urn:lane2:token:RRMT:REVOKED"},
+	}
+	for name, tc := range failures {
+		rec, _ := settle(tc.body)
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != tc.status || body.Reason != tc.reason {
+			t.Fatalf("%s: expected %d %s, got %d %s", name, tc.status, tc.reason, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestMandalaReceiptMustMatchSchema(t *testing.T) {
+	mandala := Mandala{ProofTypes: []string{"zkp_sanctions"}}
+	receipt := `{"proof_type":"zkp_sanctions","provider":"https://mandala.bis.org/evidence/v1","ccid":"CCID-1",` +
+		`"proof_hash":"sha256:` + strings.Repeat("ab", 32) + `","verified":true%s}`
+	for _, tc := range []struct {
+		extra string
+		want  bool
+	}{{`,"version":"1"`, true}, {``, false}} {
+		var proof MandalaProof
+		if err := json.Unmarshal([]byte(fmt.Sprintf(receipt, tc.extra)), &proof); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if got := mandala.satisfies(proof, "zkp_sanctions"); got != tc.want {
+			t.Fatalf("receipt %q: satisfies = %v, want %v", tc.extra, got, tc.want)
+		}
+	}
+}
+
+func TestVerifyMandalaEvidence(t *testing.T) {
+	const (
+		rmt      = "urn:lane2:token:RMT:EU:PSD3:3.2"
+		imt      = "urn:lane2:token:IMT:EU:SG:2025"
+		endpoint = "https://mandala.bis.org/evidence/v1"
+		window   = `"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false`
+	)
+	hash := "sha256:" + strings.Repeat("ab", 32)
+	mandala := Mandala{Endpoint: endpoint, ProofTypes: []string{"zkp_sanctions", "mpc_threshold_check", "aml_attestation"}}
+	aml := MandalaProof{ProofType: "aml_attestation", CCID: "CCID-1", ProofHash: hash, Provider: endpoint, Version: "1", Verified: true}
+	sanctions := MandalaProof{ProofType: "zkp_sanctions", CCID: "CCID-2", ProofHash: hash, Provider: endpoint, Version: "1", Verified: true}
+	with := func(p MandalaProof, edit func(*MandalaProof)) MandalaProof {
+		edit(&p)
+		return p
+	}
+	cases := []struct {
+		name     string
+		mandala  Mandala
+		proofs   []MandalaProof
+		expected string
+		failed   string
+	}{
+		{"satisfied", mandala, []MandalaProof{sanctions, aml}, "", ""},
+		{"missing", mandala, []MandalaProof{aml}, "evidence_missing:E-SANCTIONS_PROOF@mandala:zkp", "imt"},
+		{"rmtRequirement", mandala, []MandalaProof{sanctions}, "evidence_missing:E-AML_ATTESTATION@mandala:ccid", "rmt"},
+		{"unsupported", Mandala{Endpoint: endpoint, ProofTypes: []string{"zkp_sanctions"}}, []MandalaProof{sanctions, aml},
+			"evidence_missing:E-AML_ATTESTATION@mandala:ccid", "rmt"},
+		{"foreignProvider", mandala, []MandalaProof{sanctions, with(aml, func(p *MandalaProof) { p.Provider = "https://example.org" })},
+			"evidence_missing:E-AML_ATTESTATION@mandala:ccid", "rmt"},
+		{"badHash", mandala, []MandalaProof{sanctions, with(aml, func(p *MandalaProof) { p.ProofHash = "sha256:abc" })},
+			"evidence_missing:E-AML_ATTESTATION@mandala:ccid", "rmt"},
+		{"noCCID", mandala, []MandalaProof{sanctions, with(aml, func(p *MandalaProof) { p.CCID = "" })},
+			"evidence_missing:E-AML_ATTESTATION@mandala:ccid", "rmt"},
+		{"unverified", mandala, []MandalaProof{sanctions, with(aml, func(p *MandalaProof) { p.Verified = false })},
+			"evidence_missing:E-AML_ATTESTATION@mandala:ccid", "rmt"},
+		{"schemaInvalid", Mandala{ProofTypes: mandala.ProofTypes}, []MandalaProof{sanctions, with(aml, func(p *MandalaProof) { p.Provider = "mandala-node" })},
+			"evidence_missing:E-AML_ATTESTATION@mandala:ccid", "rmt"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := happyTokens()
+			tokens[rmt] = `{` + window + `,"evidence_requirements":["E-AML_ATTESTATION@mandala:ccid","E-KYC_FILE@pdp:local"]}`
+			tokens[imt] = `{` + window + `,"evidence_requirements":["E-SANCTIONS_PROOF@mandala:zkp"],` +
+				`"references":{"rmt_a":"urn:lane2:token:RMT:EU:PSD3:3.2","rmt_b":"urn:lane2:token:RMT:SG:PSD3:3.2"}}`
+			payload := VerifyRequest{Proofs: tc.proofs}
+			payload.Tokens.RMT = rmt
+			payload.Tokens.IMT = imt
+			payload.Tokens.CORT = "urn:lane2:token:CORT:VODAFONE.VISA:2025"
+			payload.Tokens.PSRT = "urn:lane2:token:PSRT:VISA:ACQ-123"
+			body, _ := json.Marshal(payload)
+			rec := httptest.NewRecorder()
+			NewService(1, &stubVerifier{tokens: tokens}, Options{Mandala: tc.mandala}).
+				HandleVerify(rec, httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body)))
+			var resp struct {
+				VerifyResponse
+				Type string `json:"type"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("unmarshal resp: %v", err)
+			}
+			if tc.expected == "" {
+				if rec.Code != http.StatusOK || !resp.Valid || resp.Checks[0].Evidence != CheckPass || resp.Checks[1].Evidence != CheckPass {
+					t.Fatalf("unexpected failure %s", rec.Body.String())
+				}
+				if resp.Checks[2].Evidence != CheckSkipped {
+					t.Fatalf("expected evidence skipped for cort, got %q", resp.Checks[2].Evidence)
+				}
+				return
+			}
+			if rec.Code != http.StatusForbidden || resp.Reason != tc.expected || resp.Type != problem.EvidenceMissing.URI() {
+				t.Fatalf("expected %s, got %d %s", tc.expected, rec.Code, rec.Body.String())
+			}
+			for _, check := range resp.Checks {
+				if failed := check.Role == tc.failed; failed != (check.Evidence == CheckFail) {
+					t.Fatalf("unexpected %s evidence status %q", check.Role, check.Evidence)
+				}
 			}
 		})
 	}
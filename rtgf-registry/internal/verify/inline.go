@@ -0,0 +1,238 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// TokenSlots carries one token per role. Each slot holds either a token URI
+// resolved from the registry index or an inline token signed as a compact
+// JWS string or a JSON-serialised JWS object.
+type TokenSlots struct {
+	RMT  string `json:"rmt"`
+	IMT  string `json:"imt"`
+	CORT string `json:"cort"`
+	PSRT string `json:"psrt"`
+	RRMT string `json:"rrmt,omitempty"`
+	AMLS string `json:"amls,omitempty"`
+	AMLV string `json:"amlv,omitempty"`
+}
+
+// slotValue accepts a JSON string or, for JSON-serialised JWS, an object,
+// which is kept as its raw text.
+type slotValue string
+
+func (v *slotValue) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, data); err != nil {
+			return err
+		}
+		*v = slotValue(buf.String())
+		return nil
+	}
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("token slot must be a URI, compact JWS or JWS object: %w", err)
+	}
+	if s != nil {
+		*v = slotValue(*s)
+	}
+	return nil
+}
+
+func (t *TokenSlots) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		RMT  slotValue `json:"rmt"`
+		IMT  slotValue `json:"imt"`
+		CORT slotValue `json:"cort"`
+		PSRT slotValue `json:"psrt"`
+		RRMT slotValue `json:"rrmt"`
+		AMLS slotValue `json:"amls"`
+		AMLV slotValue `json:"amlv"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*t = TokenSlots{
+		RMT: string(raw.RMT), IMT: string(raw.IMT), CORT: string(raw.CORT), PSRT: string(raw.PSRT),
+		RRMT: string(raw.RRMT), AMLS: string(raw.AMLS), AMLV: string(raw.AMLV),
+	}
+	return nil
+}
+
+// isInline reports whether a slot holds a JWS rather than a URI. Compact JWS
+// segments are base64url and never contain the ':' every URI has.
+func isInline(value string) bool {
+	return strings.HasPrefix(value, "{") ||
+		(strings.Count(value, ".") == 2 && !strings.Contains(value, ":"))
+}
+
+// inlineURI names an inline token by its `uri` claim, then its type-specific
+// id claim (e.g. `rmt_id`), falling back to a per-request placeholder.
+func inlineURI(role string, payload json.RawMessage) string {
+	var claims map[string]json.RawMessage
+	_ = json.Unmarshal(payload, &claims)
+	var typ string
+	_ = json.Unmarshal(claims["type"], &typ)
+	for _, key := range []string{"uri", strings.ToLower(typ) + "_id"} {
+		var uri string
+		if err := json.Unmarshal(claims[key], &uri); err == nil && strings.TrimSpace(uri) != "" {
+			return strings.TrimSpace(uri)
+		}
+	}
+	return "urn:lane2:inline:" + role
+}
+
+// inlineType returns the token's `type` claim, defaulting to its role.
+func inlineType(role string, payload json.RawMessage) string {
+	var claims struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &claims); err == nil && claims.Type != "" {
+		return claims.Type
+	}
+	return strings.ToUpper(role)
+}
+
+// resolveInline verifies every inline token against the trusted keys and its
+// type's JSON Schema, records its signature status and rewrites its slot to
+// the token's URI so later stages treat it like an indexed token. It returns verifier overlaid with the
+// inline payloads, and false when any inline token was rejected.
+func (s *Service) resolveInline(snap *Snapshot, req *VerifyRequest, res *verifyResult, checkFor func(string) *TokenCheck) (TokenVerifier, bool) {
+	verifier := snap.Verifier
+	tokens := make(map[string]json.RawMessage)
+	resolved := true
+	var keys verifylib.KeySet
+	for _, role := range tokenRoles {
+		check := checkFor(role.name)
+		if check == nil || !isInline(check.URI) {
+			continue
+		}
+		if keys == nil {
+			keys = s.keys(snap)
+		}
+		payload, err := verifylib.VerifyJWS([]byte(check.URI), keys)
+		check.URI = ""
+		*role.slot(req) = ""
+		if err != nil {
+			check.Signature = CheckFail
+			if errors.Is(err, verifylib.ErrJWSMalformed) {
+				res.fail(check, "inline_malformed:"+role.name)
+			} else {
+				res.fail(check, "signature_invalid:"+role.name)
+			}
+			resolved = false
+			continue
+		}
+		check.Signature = CheckPass
+		check.URI = inlineURI(role.name, payload)
+		*role.slot(req) = check.URI
+		if violations := verifylib.ValidateSchema(inlineType(role.name, payload), payload); len(violations) > 0 {
+			check.Violations = violations
+			res.fail(check, "schema_invalid:"+check.URI)
+			resolved = false
+			continue
+		}
+		if reason, indexed := indexedConflict(verifier, check.URI, payload); indexed {
+			// The registry copy stays authoritative for indexed URIs.
+			if reason != "" {
+				res.fail(check, reason)
+			}
+			continue
+		}
+		tokens[check.URI] = payload
+	}
+	if len(tokens) == 0 {
+		return verifier, resolved
+	}
+	return &inlineVerifier{base: verifier, inline: verifylib.NewStaticVerifierFromTokens(tokens)}, resolved
+}
+
+// indexedConflict compares an inline token with the registry copy of the
+// same URI, reporting whether one exists. A revoked indexed token fails with
+// token_revoked even when an older signed copy claims otherwise, and any other
+// difference in canonical hash fails with inline_mismatch.
+func indexedConflict(verifier TokenVerifier, uri string, payload json.RawMessage) (string, bool) {
+	if verifier == nil {
+		return "", false
+	}
+	indexed, ok := verifier.Token(uri)
+	if !ok {
+		return "", false
+	}
+	want, errIndexed := verifylib.CanonicalDigest(indexed)
+	got, errInline := verifylib.CanonicalDigest(payload)
+	if errIndexed == nil && errInline == nil && want == got {
+		return "", true
+	}
+	var meta struct {
+		Revoked bool `json:"revoked"`
+	}
+	if json.Unmarshal(indexed, &meta) == nil && meta.Revoked {
+		return "token_revoked:" + uri, true
+	}
+	return "inline_mismatch:" + uri, true
+}
+
+// inlineVerifier serves inline tokens ahead of the registry index, so a
+// request made only of inline tokens needs no registry lookup.
+type inlineVerifier struct {
+	base   TokenVerifier
+	inline *verifylib.StaticVerifier
+}
+
+func (v *inlineVerifier) Token(uri string) (json.RawMessage, bool) {
+	if payload, ok := v.inline.Token(uri); ok {
+		return payload, true
+	}
+	if v.base == nil {
+		return nil, false
+	}
+	return v.base.Token(uri)
+}
+
+func (v *inlineVerifier) verify(ctx context.Context, uri string, check func(TokenVerifier, context.Context, string) error) error {
+	if _, ok := v.inline.Token(uri); ok {
+		return check(v.inline, ctx, uri)
+	}
+	if v.base == nil {
+		return fmt.Errorf("token %s not found", uri)
+	}
+	return check(v.base, ctx, uri)
+}
+
+func (v *inlineVerifier) VerifyRRMT(ctx context.Context, uri string) error {
+	return v.verify(ctx, uri, TokenVerifier.VerifyRRMT)
+}
+
+func (v *inlineVerifier) VerifyRMT(ctx context.Context, uri string) error {
+	return v.verify(ctx, uri, TokenVerifier.VerifyRMT)
+}
+
+func (v *inlineVerifier) VerifyIMT(ctx context.Context, uri string) error {
+	return v.verify(ctx, uri, TokenVerifier.VerifyIMT)
+}
+
+func (v *inlineVerifier) VerifyCORT(ctx context.Context, uri string) error {
+	return v.verify(ctx, uri, TokenVerifier.VerifyCORT)
+}
+
+func (v *inlineVerifier) VerifyPSRT(ctx context.Context, uri string) error {
+	return v.verify(ctx, uri, TokenVerifier.VerifyPSRT)
+}
+
+func (v *inlineVerifier) VerifyAMLS(ctx context.Context, uri string) error {
+	return v.verify(ctx, uri, TokenVerifier.VerifyAMLS)
+}
+
+func (v *inlineVerifier) VerifyAMLV(ctx context.Context, uri string) error {
+	return v.verify(ctx, uri, TokenVerifier.VerifyAMLV)
+}
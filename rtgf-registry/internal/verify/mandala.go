@@ -0,0 +1,159 @@
+package verify
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// MandalaProof is a BIS Project Mandala proof receipt presented as evidence
+// (docs/mandala/alignment.md). Receipts must conform to
+// schemas/mandala/mandala-proof.schema.json.
+type MandalaProof struct {
+	ProofType string `json:"proof_type"`
+	CCID      string `json:"ccid"`
+	// ProofHash is the "sha256:<hex>" digest of the proof bundle.
+	ProofHash string `json:"proof_hash"`
+	// Provider is the Mandala node that issued the receipt; it must be the
+	// registry's announced endpoint when one is announced.
+	Provider string `json:"provider"`
+	Version  string `json:"version"`
+	// Verified reports whether the Mandala node verified the proof; receipts
+	// that were not verified never satisfy a requirement.
+	Verified bool `json:"verified"`
+
+	// raw is the receipt as submitted, validated against the schema so that
+	// absent required members are not mistaken for zero values.
+	raw json.RawMessage
+}
+
+// UnmarshalJSON decodes the receipt and keeps the submitted document.
+func (p *MandalaProof) UnmarshalJSON(data []byte) error {
+	type plain MandalaProof
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	p.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// document returns the receipt as submitted, or its encoding when it was
+// built in memory.
+func (p MandalaProof) document() []byte {
+	if p.raw != nil {
+		return p.raw
+	}
+	data, _ := json.Marshal(p)
+	return data
+}
+
+// Mandala is the registry's /.well-known/mandala.json announcement: the proof
+// types it accepts as evidence and the Mandala endpoint issuing them.
+type Mandala struct {
+	Endpoint   string   `json:"mandala_endpoint"`
+	ProofTypes []string `json:"supported_proof_types"`
+}
+
+// ParseMandala decodes a /.well-known/mandala.json document.
+func ParseMandala(data []byte) (Mandala, error) {
+	var m Mandala
+	err := json.Unmarshal(data, &m)
+	return m, err
+}
+
+// mandalaEvidence maps evidence requirement names to the Mandala proof type
+// that satisfies them. Names not listed match the proof type of the same
+// name, lowercased.
+var mandalaEvidence = map[string]string{
+	"SANCTIONS_PROOF": "zkp_sanctions",
+	"THRESHOLD_CHECK": "mpc_threshold_check",
+}
+
+// mandalaProofType returns the proof type required by an evidence requirement
+// of the form "E-<NAME>@mandala:<kind>", or false for requirements that are
+// not satisfied by Mandala proofs.
+func mandalaProofType(requirement string) (string, bool) {
+	name, source, ok := strings.Cut(requirement, "@")
+	if !ok || !strings.HasPrefix(source, "mandala:") {
+		return "", false
+	}
+	name = strings.TrimPrefix(name, "E-")
+	if proofType, ok := mandalaEvidence[name]; ok {
+		return proofType, true
+	}
+	return strings.ToLower(name), true
+}
+
+// MandalaRequirements returns the Mandala evidence_requirements the token at
+// uri declares; tokens that are missing or undecodable declare none.
+func MandalaRequirements(provider TokenVerifier, uri string) []string {
+	var token struct {
+		EvidenceRequirements []string `json:"evidence_requirements"`
+	}
+	if err := decodeToken(provider, uri, &token); err != nil {
+		return nil
+	}
+	var out []string
+	for _, requirement := range token.EvidenceRequirements {
+		if _, ok := mandalaProofType(requirement); ok {
+			out = append(out, requirement)
+		}
+	}
+	return out
+}
+
+// satisfies reports whether p is a schema-valid, verified receipt of
+// proofType issued by the announced endpoint.
+func (m Mandala) satisfies(p MandalaProof, proofType string) bool {
+	if p.ProofType != proofType || !slices.Contains(m.ProofTypes, proofType) || p.CCID == "" || !p.Verified {
+		return false
+	}
+	if len(verifylib.ValidateMandalaProof(p.document())) > 0 {
+		return false
+	}
+	if m.Endpoint != "" && p.Provider != m.Endpoint {
+		return false
+	}
+	digest, ok := strings.CutPrefix(p.ProofHash, "sha256:")
+	if _, err := hex.DecodeString(digest); !ok || len(digest) != 64 || err != nil {
+		return false
+	}
+	return true
+}
+
+// evidenceFailures checks every Mandala evidence_requirement of the RMT and
+// IMT against the request's proof receipts. A requirement fails when no
+// receipt of a supported proof type satisfies it; requirements naming other
+// evidence sources are left to the PDP.
+func evidenceFailures(provider TokenVerifier, req VerifyRequest, mandala Mandala) []roleFailure {
+	var failures []roleFailure
+	for _, role := range []struct{ name, uri string }{{"rmt", req.Tokens.RMT}, {"imt", req.Tokens.IMT}} {
+		var token struct {
+			EvidenceRequirements []string `json:"evidence_requirements"`
+		}
+		if err := decodeToken(provider, role.uri, &token); err != nil {
+			failures = append(failures, roleFailure{role.name, err.Error()})
+			continue
+		}
+		for _, requirement := range token.EvidenceRequirements {
+			proofType, ok := mandalaProofType(requirement)
+			if !ok {
+				continue
+			}
+			satisfied := false
+			for _, proof := range req.Proofs {
+				if mandala.satisfies(proof, proofType) {
+					satisfied = true
+					break
+				}
+			}
+			if !satisfied {
+				failures = append(failures, roleFailure{role.name, "evidence_missing:" + requirement})
+			}
+		}
+	}
+	return failures
+}
@@ -0,0 +1,61 @@
+package verify
+
+import (
+	"math/big"
+	"strings"
+)
+
+// currencyExponents lists ISO 4217 currencies whose minor unit is not 1/100.
+var currencyExponents = map[string]int{
+	"BHD": 3, "CLP": 0, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KRW": 0, "KWD": 3, "OMR": 3, "TND": 3, "VND": 0,
+}
+
+// minorUnits returns the number of decimal places of currency (2 by default).
+func minorUnits(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// parseAmount parses a non-negative decimal string with at most places
+// fractional digits. Exponents and fractions such as "1/3" are rejected.
+func parseAmount(s string, places int) (*big.Rat, bool) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || len(frac) > places || strings.Contains(s, ".") && frac == "" {
+		return nil, false
+	}
+	for _, c := range whole + frac {
+		if c < '0' || c > '9' {
+			return nil, false
+		}
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// percentOf returns amount * pct / 100.
+func percentOf(amount, pct *big.Rat) *big.Rat {
+	out := new(big.Rat).Mul(amount, pct)
+	return out.Quo(out, big.NewRat(100, 1))
+}
+
+// roundMinor rounds r half away from zero to places decimal places.
+func roundMinor(r *big.Rat, places int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+	num := new(big.Int).Abs(scaled.Num())
+	q, rem := new(big.Int).QuoRem(num, scaled.Denom(), new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(scaled.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if scaled.Sign() < 0 {
+		q.Neg(q)
+	}
+	return new(big.Rat).SetFrac(q, scale)
+}
+
+// formatMinor renders an amount already rounded to places decimal places.
+func formatMinor(r *big.Rat, places int) string {
+	return r.FloatString(places)
+}
@@ -0,0 +1,90 @@
+package verify
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// reasonProblems maps verification reason codes to registered problem types.
+// Reasons not listed fall back to imt_verification_failed (RTGF-REQ-020 step 7).
+var reasonProblems = map[string]problem.Type{
+	"invalid_request":    problem.InvalidRequest,
+	"invalid_quote":      problem.InvalidRequest,
+	"invalid_settlement": problem.InvalidRequest,
+	"invalid_context":    problem.InvalidRequest,
+	"invalid_at":         problem.InvalidRequest,
+
+	"historical_verification_disabled": problem.InvalidRequest,
+	"missing_rmt":                      problem.MissingToken,
+	"missing_imt":                      problem.MissingToken,
+	"missing_cort":                     problem.MissingToken,
+	"missing_psrt":                     problem.MissingToken,
+	"missing_amls":                     problem.MissingToken,
+	"missing_amlv":                     problem.MissingToken,
+	"metadata_missing":                 problem.TokenNotFound,
+	"metadata_invalid":                 problem.TokenMalformed,
+	"invalid_nbf":                      problem.TokenMalformed,
+	"invalid_exp":                      problem.TokenMalformed,
+	"token_revoked":                    problem.TokenRevoked,
+	"reference_revoked":                problem.TokenRevoked,
+	"token_expired":                    problem.TokenExpired,
+	"token_not_yet_valid":              problem.TokenNotYetValid,
+	"invalid_rrmt":                     problem.TokenTypeInvalid,
+	"invalid_rmt":                      problem.TokenTypeInvalid,
+	"invalid_imt":                      problem.TokenTypeInvalid,
+	"invalid_cort":                     problem.TokenTypeInvalid,
+	"invalid_psrt":                     problem.TokenTypeInvalid,
+	"invalid_amls":                     problem.TokenTypeInvalid,
+	"invalid_amlv":                     problem.TokenTypeInvalid,
+	"inline_malformed":                 problem.TokenMalformed,
+	"schema_invalid":                   problem.TokenMalformed,
+	"signature_invalid":                problem.TokenSignatureInvalid,
+	"jti_conflict":                     problem.TokenReplayed,
+	"inline_mismatch":                  problem.TokenReplayed,
+	"jti_revoked":                      problem.TokenRevoked,
+	"replay_unavailable":               problem.Internal,
+	"missing_rrmt":                     problem.MissingToken,
+	"unknown_surcharge":                problem.InvalidRequest,
+	"pricing_invalid":                  problem.TokenMalformed,
+	"reserve_invalid":                  problem.TokenMalformed,
+	"capture_invalid":                  problem.TokenMalformed,
+	"evidence_missing":                 problem.EvidenceMissing,
+	verifylib.CORTMalformed:            problem.CORTTermsInvalid,
+	verifylib.CORTPartyInvalid:         problem.CORTTermsInvalid,
+	verifylib.CORTMissingRole:          problem.CORTTermsInvalid,
+	verifylib.CORTSplitInvalid:         problem.CORTTermsInvalid,
+	verifylib.CORTSplitParty:           problem.CORTTermsInvalid,
+	verifylib.CORTSplitTotal:           problem.CORTTermsInvalid,
+	verifylib.CORTFXInvalid:            problem.CORTTermsInvalid,
+	verifylib.CORTPayoutInvalid:        problem.CORTTermsInvalid,
+}
+
+// reasonCode strips the token URI suffix from reasons such as "token_expired:<uri>".
+func reasonCode(reason string) string {
+	code, _, _ := strings.Cut(reason, ":")
+	return code
+}
+
+func problemForReason(reason string) problem.Type {
+	if t, ok := reasonProblems[reasonCode(reason)]; ok {
+		return t
+	}
+	return problem.IMTVerificationFailed
+}
+
+// respondFailure renders a failed verification as problem+json while keeping
+// the VerifyResponse members as extensions for existing clients.
+func respondFailure(w http.ResponseWriter, r *http.Request, resp VerifyResponse) {
+	details := problem.New(problemForReason(resp.Reason), resp.Reason).
+		With("valid", resp.Valid).
+		With("revEpoch", resp.RevEpoch).
+		With("reason", resp.Reason).
+		With("checks", resp.Checks)
+	if resp.Receipt != "" {
+		details.With("receipt", resp.Receipt)
+	}
+	details.Write(w, r)
+}
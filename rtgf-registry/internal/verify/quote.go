@@ -0,0 +1,209 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+)
+
+// QuoteRequest asks for the fees an RRMT charges on one transaction.
+type QuoteRequest struct {
+	RRMT string `json:"rrmt"`
+	// Amount is a decimal string in major units with at most the currency's
+	// minor-unit count of fractional digits, e.g. "125.50" for EUR.
+	Amount string `json:"amount"`
+	// Currency defaults to the RRMT currency and must match it when set.
+	Currency string `json:"currency,omitempty"`
+	// Flags names the RRMT surcharges that apply, e.g. "cross_border".
+	Flags []string `json:"flags,omitempty"`
+}
+
+// Quote is the fee breakdown for a QuoteRequest. Hash is the canonical
+// digest of the RRMT it was computed from. Monetary values are decimal
+// strings in major units, rounded to the currency's minor unit; rates echo
+// the RRMT as written.
+type Quote struct {
+	RRMT       string      `json:"rrmt"`
+	Version    string      `json:"version,omitempty"`
+	Hash       string      `json:"hash"`
+	RevEpoch   uint64      `json:"revEpoch"`
+	Currency   string      `json:"currency"`
+	Amount     string      `json:"amount"`
+	Tier       QuoteTier   `json:"tier"`
+	Fee        string      `json:"fee"`
+	Surcharges string      `json:"surcharges"`
+	VAT        string      `json:"vat"`
+	Total      string      `json:"total"`
+	Breakdown  []QuoteLine `json:"breakdown"`
+}
+
+// QuoteTier identifies the pricing tier applied, by index into pricing.tiers.
+type QuoteTier struct {
+	Index  int    `json:"index"`
+	Min    string `json:"min,omitempty"`
+	Max    string `json:"max,omitempty"`
+	FeePct string `json:"fee_pct"`
+	MinFee string `json:"min_fee,omitempty"`
+}
+
+// QuoteLine is one component of a quote: Amount is Pct percent of Basis,
+// rounded, unless Note says a floor applied.
+type QuoteLine struct {
+	Item   string `json:"item"`
+	Basis  string `json:"basis"`
+	Pct    string `json:"pct"`
+	Amount string `json:"amount"`
+	Note   string `json:"note,omitempty"`
+}
+
+type rrmtQuote struct {
+	Version  string      `json:"version"`
+	Currency string      `json:"currency"`
+	Pricing  rrmtPricing `json:"pricing"`
+	Tax      struct {
+		VATPct json.Number `json:"vat_pct"`
+	} `json:"tax"`
+}
+
+// HandleQuote prices a transaction from a verified RRMT.
+func (s *Service) HandleQuote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		problem.Write(w, r, problem.MethodNotAllowed, "")
+		return
+	}
+	defer r.Body.Close()
+	var req QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, problem.InvalidRequest, "quote request must be a JSON object")
+		return
+	}
+	revEpoch := s.revEpoch.Load()
+	quote, reason := s.quote(r.Context(), s.current().Verifier, req)
+	if reason != "" {
+		problem.New(problemForReason(reason), reason).
+			With("reason", reason).
+			With("revEpoch", revEpoch).
+			Write(w, r)
+		return
+	}
+	quote.RevEpoch = revEpoch
+	respondJSON(w, quote)
+}
+
+// quote computes the fee, each flagged surcharge and VAT on their sum. Every
+// line is exact until it is rounded half away from zero to the currency's
+// minor unit; VAT is charged on the rounded fee and surcharges, and the total
+// is the sum of the rounded lines, so the breakdown always adds up. RRMT
+// rates are percentages: fee_pct 0.45 charges 0.45% of the amount.
+func (s *Service) quote(ctx context.Context, verifier TokenVerifier, req QuoteRequest) (Quote, string) {
+	check, reason := s.checkToken(ctx, verifier, "rrmt", req.RRMT)
+	if reason != "" {
+		return Quote{}, reason
+	}
+	var rrmt rrmtQuote
+	if err := decodeToken(verifier, req.RRMT, &rrmt); err != nil {
+		return Quote{}, err.Error()
+	}
+	currency := strings.ToUpper(rrmt.Currency)
+	if req.Currency != "" && !strings.EqualFold(req.Currency, currency) {
+		return Quote{}, "context_mismatch:currency"
+	}
+	if currency == "" {
+		currency = strings.ToUpper(req.Currency)
+	}
+	places := minorUnits(currency)
+	amount, ok := parseAmount(req.Amount, places)
+	if !ok {
+		return Quote{}, "invalid_quote:amount"
+	}
+	if len(rrmt.Pricing.Tiers) == 0 {
+		return Quote{}, "pricing_invalid:" + req.RRMT
+	}
+	index, ok := rrmt.Pricing.tier(amount)
+	if !ok {
+		return Quote{}, "context_mismatch:amount"
+	}
+	tier := rrmt.Pricing.Tiers[index]
+	rate := func(n json.Number) (*big.Rat, bool) {
+		if n == "" {
+			return new(big.Rat), true
+		}
+		return new(big.Rat).SetString(n.String())
+	}
+
+	q := Quote{
+		RRMT:     req.RRMT,
+		Version:  rrmt.Version,
+		Hash:     check.Hash,
+		Currency: currency,
+		Amount:   formatMinor(amount, places),
+		Tier: QuoteTier{
+			Index: index, Min: tier.Min.String(), Max: tier.Max.String(),
+			FeePct: tier.FeePct.String(), MinFee: tier.MinFee.String(),
+		},
+	}
+	feePct, okFee := rate(tier.FeePct)
+	minFee, okMin := rate(tier.MinFee)
+	vatPct, okVAT := rate(rrmt.Tax.VATPct)
+	if !okFee || !okMin || !okVAT {
+		return Quote{}, "pricing_invalid:" + req.RRMT
+	}
+	fee := roundMinor(percentOf(amount, feePct), places)
+	feeLine := QuoteLine{Item: "fee", Basis: q.Amount, Pct: tier.FeePct.String()}
+	if floor := roundMinor(minFee, places); fee.Cmp(floor) < 0 {
+		fee, feeLine.Note = floor, "min_fee"
+	}
+	feeLine.Amount = formatMinor(fee, places)
+	q.Breakdown = append(q.Breakdown, feeLine)
+
+	surcharges := new(big.Rat)
+	applied := make(map[string]bool, len(req.Flags))
+	for _, flag := range req.Flags {
+		if applied[flag] {
+			continue
+		}
+		applied[flag] = true
+		found := false
+		for _, surcharge := range rrmt.Pricing.Surcharges {
+			if surcharge.Code != flag {
+				continue
+			}
+			pct, ok := rate(surcharge.Pct)
+			if !ok {
+				return Quote{}, "pricing_invalid:" + req.RRMT
+			}
+			line := roundMinor(percentOf(amount, pct), places)
+			surcharges.Add(surcharges, line)
+			q.Breakdown = append(q.Breakdown, QuoteLine{
+				Item: "surcharge:" + flag, Basis: q.Amount, Pct: surcharge.Pct.String(), Amount: formatMinor(line, places),
+			})
+			found = true
+			break
+		}
+		if !found {
+			return Quote{}, "unknown_surcharge:" + flag
+		}
+	}
+
+	fees := new(big.Rat).Add(fee, surcharges)
+	vat := roundMinor(percentOf(fees, vatPct), places)
+	vatBasis := formatMinor(fees, places)
+	q.Breakdown = append(q.Breakdown, QuoteLine{Item: "vat", Basis: vatBasis, Pct: rateString(rrmt.Tax.VATPct), Amount: formatMinor(vat, places)})
+
+	q.Fee = formatMinor(fee, places)
+	q.Surcharges = formatMinor(surcharges, places)
+	q.VAT = formatMinor(vat, places)
+	q.Total = formatMinor(new(big.Rat).Add(fees, vat), places)
+	return q, ""
+}
+
+func rateString(n json.Number) string {
+	if n == "" {
+		return "0"
+	}
+	return n.String()
+}
@@ -0,0 +1,83 @@
+package verify
+
+import (
+	"encoding/json"
+	"time"
+
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// ReceiptType is the JOSE `typ` of verification receipts.
+const ReceiptType = "rtgf-receipt+jws"
+
+// Receipt is the payload of the compact JWS returned as
+// VerifyResponse.Receipt. It is signed with the registry key, so it can be
+// checked offline against /jwks.json and embedded in evidence bundles.
+type Receipt struct {
+	Issuer string `json:"iss,omitempty"`
+	// IssuedAt is when the receipt was signed, in seconds since the epoch.
+	IssuedAt int64 `json:"iat"`
+	// At is the instant the tokens were verified against (RFC 3339).
+	At string `json:"at"`
+	// RequestHash is the canonical sha256 digest of the request as submitted,
+	// inline tokens included.
+	RequestHash string         `json:"request_hash"`
+	Tokens      []ReceiptToken `json:"tokens"`
+	RevEpoch    uint64         `json:"revEpoch"`
+	Valid       bool           `json:"valid"`
+	// Reasons lists the decisive reason first, then every other distinct
+	// per-token failure.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// ReceiptToken binds a verified role to the token URI and canonical hash.
+type ReceiptToken struct {
+	Role string `json:"role"`
+	URI  string `json:"uri,omitempty"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// receipt signs the outcome of req, or returns "" without a signing key.
+func (s *Service) receipt(res verifyResult, revEpoch uint64, req VerifyRequest) string {
+	if s.signer == nil {
+		return ""
+	}
+	receipt := Receipt{
+		Issuer:   s.issuer,
+		IssuedAt: s.clock.Now().Unix(),
+		At:       res.at.Format(time.RFC3339),
+		Tokens:   make([]ReceiptToken, 0, len(res.checks)),
+		RevEpoch: revEpoch,
+		Valid:    res.valid,
+	}
+	if raw, err := json.Marshal(req); err == nil {
+		receipt.RequestHash, _ = verifylib.CanonicalDigest(raw)
+	}
+	seen := make(map[string]bool)
+	for _, reason := range append([]string{res.reason}, checkReasons(res.checks)...) {
+		if reason != "" && !seen[reason] {
+			seen[reason] = true
+			receipt.Reasons = append(receipt.Reasons, reason)
+		}
+	}
+	for _, check := range res.checks {
+		receipt.Tokens = append(receipt.Tokens, ReceiptToken{Role: check.Role, URI: check.URI, Hash: check.Hash})
+	}
+	raw, err := json.Marshal(receipt)
+	if err != nil {
+		return ""
+	}
+	payload, err := verifylib.Canonicalize(raw)
+	if err != nil {
+		return ""
+	}
+	return s.signer.Sign(payload, ReceiptType)
+}
+
+func checkReasons(checks []TokenCheck) []string {
+	reasons := make([]string, 0, len(checks))
+	for _, check := range checks {
+		reasons = append(reasons, check.Reason)
+	}
+	return reasons
+}
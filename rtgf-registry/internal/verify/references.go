@@ -0,0 +1,87 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// tokenReferences holds the reference members carried by IMT and CORT payloads.
+type tokenReferences struct {
+	References struct {
+		RMTA string `json:"rmt_a"`
+		RMTB string `json:"rmt_b"`
+		RMT  string `json:"rmt"`
+		RRMT string `json:"rrmt"`
+	} `json:"references"`
+}
+
+// roleFailure attributes a failure reason to the token role it concerns.
+type roleFailure struct {
+	role   string
+	reason string
+}
+
+// referenceFailures checks that the submitted tuple is internally consistent:
+// the RMT must be one of those the IMT references and the one the CORT is bound
+// to, a submitted RRMT must match the CORT's, and every referenced token must be
+// present in the registry and unrevoked. It evaluates the IMT's and then the
+// CORT's references and returns every failure, attributed to the referencing
+// token.
+func referenceFailures(provider TokenVerifier, req VerifyRequest) []roleFailure {
+	var failures []roleFailure
+	if imt, err := loadReferences(provider, req.Tokens.IMT); err != nil {
+		failures = append(failures, roleFailure{"imt", err.Error()})
+	} else {
+		if req.Tokens.RMT != imt.References.RMTA && req.Tokens.RMT != imt.References.RMTB {
+			failures = append(failures, roleFailure{"imt", "rmt_not_referenced:" + req.Tokens.IMT})
+		}
+		failures = appendReferenced(failures, provider, "imt", imt.References.RMTA, imt.References.RMTB)
+	}
+	if cort, err := loadReferences(provider, req.Tokens.CORT); err != nil {
+		failures = append(failures, roleFailure{"cort", err.Error()})
+	} else {
+		if ref := cort.References.RMT; ref != "" && ref != req.Tokens.RMT {
+			failures = append(failures, roleFailure{"cort", "rmt_not_referenced:" + req.Tokens.CORT})
+		}
+		if ref := cort.References.RRMT; ref != "" && req.Tokens.RRMT != "" && ref != req.Tokens.RRMT {
+			failures = append(failures, roleFailure{"cort", "rrmt_not_referenced:" + req.Tokens.CORT})
+		}
+		failures = appendReferenced(failures, provider, "cort", cort.References.RMT, cort.References.RRMT)
+	}
+	return failures
+}
+
+func appendReferenced(failures []roleFailure, provider TokenVerifier, role string, uris ...string) []roleFailure {
+	for _, uri := range uris {
+		if uri == "" {
+			continue
+		}
+		if err := checkReferenced(provider, uri); err != nil {
+			failures = append(failures, roleFailure{role, err.Error()})
+		}
+	}
+	return failures
+}
+
+func loadReferences(provider TokenVerifier, uri string) (tokenReferences, error) {
+	var refs tokenReferences
+	err := decodeToken(provider, uri, &refs)
+	return refs, err
+}
+
+func checkReferenced(provider TokenVerifier, uri string) error {
+	payload, ok := provider.Token(uri)
+	if !ok || len(payload) == 0 {
+		return fmt.Errorf("reference_missing:%s", uri)
+	}
+	var meta struct {
+		Revoked bool `json:"revoked"`
+	}
+	if err := json.Unmarshal(payload, &meta); err != nil {
+		return fmt.Errorf("metadata_invalid:%s", uri)
+	}
+	if meta.Revoked {
+		return fmt.Errorf("reference_revoked:%s", uri)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+type replayClaims struct {
+	Issuer  string `json:"iss"`
+	JTI     string `json:"jti"`
+	TTL     int64  `json:"ttl_sec"`
+	Revoked bool   `json:"revoked"`
+}
+
+// checkReplay records the token's jti in the replay cache (RTGF-REQ-021) and
+// returns a reason when the jti was seen with another hash or after its
+// revocation. Tokens without a jti are not tracked. The retention computed
+// here only applies on first sight; the cache keeps that expiry for repeats.
+func (s *Service) checkReplay(provider TokenVerifier, check *TokenCheck) string {
+	payload, ok := provider.Token(check.URI)
+	if !ok {
+		return ""
+	}
+	var claims replayClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.JTI == "" {
+		return ""
+	}
+	now := s.clock.Now()
+	err := verifylib.CheckReplay(s.replay, verifylib.ReplayEntry{
+		Issuer:  claims.Issuer,
+		JTI:     claims.JTI,
+		Hash:    check.Hash,
+		Revoked: claims.Revoked,
+		Expires: now.Add(verifylib.ReplayRetention(time.Duration(claims.TTL) * time.Second)),
+	}, now)
+	switch {
+	case err == nil:
+		check.Replay = CheckPass
+		return ""
+	case errors.Is(err, verifylib.ErrReplayConflict):
+		check.Replay = CheckFail
+		return "jti_conflict:" + check.URI
+	case errors.Is(err, verifylib.ErrReplayRevoked):
+		check.Replay = CheckFail
+		return "jti_revoked:" + check.URI
+	default:
+		// Fail closed: an unrecorded jti could later be replayed unnoticed.
+		check.Replay = CheckFail
+		return "replay_unavailable:" + check.URI
+	}
+}
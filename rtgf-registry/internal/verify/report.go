@@ -0,0 +1,310 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	verifylib "github.com/kevin-biot/rtgf/rtgf-verify-lib"
+)
+
+// Check statuses reported in TokenCheck.
+const (
+	CheckPass    = "pass"
+	CheckFail    = "fail"
+	CheckSkipped = "skipped"
+)
+
+// TokenCheck reports every check applied to one submitted token role.
+type TokenCheck struct {
+	Role       string `json:"role"`
+	URI        string `json:"uri,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	Status     string `json:"status"`
+	Window     string `json:"window"`
+	Revocation string `json:"revocation"`
+	TypeCheck  string `json:"type_check"`
+	Signature  string `json:"signature"`
+	// Terms covers CORT commercial-terms validation.
+	Terms      string `json:"terms"`
+	Replay     string `json:"replay"`
+	References string `json:"references"`
+	// Evidence covers RMT/IMT evidence_requirements.
+	Evidence string `json:"evidence"`
+	// Reason is the first failure recorded for this token.
+	Reason string `json:"reason,omitempty"`
+	// Violations locates the JSON Schema violations of an inline token.
+	Violations []verifylib.SchemaViolation `json:"violations,omitempty"`
+}
+
+// tokenRole binds a VerifyRequest slot to its type check.
+type tokenRole struct {
+	name     string
+	required bool
+	slot     func(*VerifyRequest) *string
+	verify   func(TokenVerifier, context.Context, string) error
+}
+
+// tokenRoles fixes the evaluation and reporting order of the token slots.
+var tokenRoles = []tokenRole{
+	{"rmt", true, func(r *VerifyRequest) *string { return &r.Tokens.RMT }, TokenVerifier.VerifyRMT},
+	{"imt", true, func(r *VerifyRequest) *string { return &r.Tokens.IMT }, TokenVerifier.VerifyIMT},
+	{"cort", true, func(r *VerifyRequest) *string { return &r.Tokens.CORT }, TokenVerifier.VerifyCORT},
+	{"psrt", true, func(r *VerifyRequest) *string { return &r.Tokens.PSRT }, TokenVerifier.VerifyPSRT},
+	{"rrmt", false, func(r *VerifyRequest) *string { return &r.Tokens.RRMT }, TokenVerifier.VerifyRRMT},
+	{"amls", false, func(r *VerifyRequest) *string { return &r.Tokens.AMLS }, TokenVerifier.VerifyAMLS},
+	{"amlv", false, func(r *VerifyRequest) *string { return &r.Tokens.AMLV }, TokenVerifier.VerifyAMLV},
+}
+
+// verifyResult is the outcome of evaluating one VerifyRequest.
+type verifyResult struct {
+	valid  bool
+	reason string
+	checks []TokenCheck
+	// at is the instant the tokens were verified against.
+	at time.Time
+}
+
+// response renders res, signing a receipt when the service has a key.
+func (s *Service) response(res verifyResult, revEpoch uint64, req VerifyRequest) VerifyResponse {
+	return VerifyResponse{
+		Valid: res.valid, RevEpoch: revEpoch, Reason: res.reason, At: req.At, Checks: res.checks,
+		Receipt: s.receipt(res, revEpoch, req),
+	}
+}
+
+func (res *verifyResult) fail(check *TokenCheck, reason string) {
+	if res.reason == "" {
+		res.reason = reason
+	}
+	if check != nil {
+		check.Status = CheckFail
+		if check.Reason == "" {
+			check.Reason = reason
+		}
+	}
+}
+
+// evaluate runs every check against every submitted token so the report is
+// complete, in stages: presence, inline signatures, windows and revocation,
+// jti replay, type discriminators, corridor-mandated tokens, references, Mandala
+// evidence and execution context. The reason is
+// the first failure in that order, and roles are reported in tokenRoles order.
+func (s *Service) evaluate(ctx context.Context, snap *Snapshot, req VerifyRequest) verifyResult {
+	res := verifyResult{checks: make([]TokenCheck, 0, len(tokenRoles))}
+	now, reason := s.verificationTime(req)
+	if reason != "" {
+		res.reason = reason
+		res.at = s.clock.Now()
+		return res
+	}
+	res.at = now
+	index := make(map[string]int, len(tokenRoles))
+	for _, role := range tokenRoles {
+		uri := strings.TrimSpace(*role.slot(&req))
+		if uri == "" && !role.required {
+			continue
+		}
+		index[role.name] = len(res.checks)
+		res.checks = append(res.checks, TokenCheck{
+			Role: role.name, URI: uri, Status: CheckPass,
+			Window: CheckSkipped, Revocation: CheckSkipped, TypeCheck: CheckSkipped,
+			Signature: CheckSkipped, Terms: CheckSkipped, Replay: CheckSkipped, References: CheckSkipped,
+			Evidence: CheckSkipped,
+		})
+	}
+	checkFor := func(role string) *TokenCheck {
+		if i, ok := index[role]; ok {
+			return &res.checks[i]
+		}
+		return nil
+	}
+
+	complete := true
+	for _, role := range tokenRoles {
+		if check := checkFor(role.name); check != nil && check.URI == "" {
+			res.fail(check, "missing_"+role.name)
+			complete = false
+		}
+	}
+	verifier, resolved := s.resolveInline(snap, &req, &res, checkFor)
+	if !resolved {
+		complete = false
+	}
+	if verifier == nil {
+		res.valid = res.reason == ""
+		return res
+	}
+
+	for i := range res.checks {
+		if check := &res.checks[i]; check.URI != "" {
+			if reason := inspectToken(now, s.skew, verifier, check); reason != "" {
+				res.fail(check, reason)
+			}
+		}
+	}
+	for i := range res.checks {
+		if check := &res.checks[i]; check.URI != "" {
+			if reason := s.checkReplay(verifier, check); reason != "" {
+				res.fail(check, reason)
+			}
+		}
+	}
+	for _, role := range tokenRoles {
+		check := checkFor(role.name)
+		if check == nil || check.URI == "" {
+			continue
+		}
+		check.TypeCheck = CheckPass
+		err := role.verify(verifier, ctx, check.URI)
+		var terms *verifylib.CORTError
+		switch {
+		case errors.As(err, &terms):
+			check.Terms = CheckFail
+			for _, violation := range terms.Violations {
+				res.fail(check, violation.Code+":"+check.URI)
+			}
+		case err != nil:
+			check.TypeCheck = CheckFail
+			res.fail(check, "invalid_"+role.name)
+		case role.name == "cort":
+			check.Terms = CheckPass
+		}
+	}
+	if !complete {
+		return res
+	}
+	if err := validateAML(verifier, req); err != nil {
+		res.fail(checkFor("imt"), err.Error())
+	}
+	for _, role := range []string{"imt", "cort"} {
+		checkFor(role).References = CheckPass
+	}
+	for _, failure := range referenceFailures(verifier, req) {
+		check := checkFor(failure.role)
+		check.References = CheckFail
+		res.fail(check, failure.reason)
+	}
+	for _, role := range []string{"rmt", "imt"} {
+		checkFor(role).Evidence = CheckPass
+	}
+	for _, failure := range evidenceFailures(verifier, req, snap.Mandala) {
+		check := checkFor(failure.role)
+		check.Evidence = CheckFail
+		res.fail(check, failure.reason)
+	}
+	if err := validateContext(verifier, req); err != nil {
+		res.fail(nil, err.Error())
+	}
+	res.valid = res.reason == ""
+	return res
+}
+
+// verificationTime returns the service clock, or the request's `at` instant
+// when historical verification is enabled.
+func (s *Service) verificationTime(req VerifyRequest) (time.Time, string) {
+	if req.At == "" {
+		return s.clock.Now(), ""
+	}
+	if !s.historical {
+		return time.Time{}, "historical_verification_disabled"
+	}
+	at, err := time.Parse(time.RFC3339, req.At)
+	if err != nil {
+		return time.Time{}, "invalid_at"
+	}
+	return at.UTC(), ""
+}
+
+// checkToken runs the window, revocation and type checks of /verify, CORT
+// terms included, on a single indexed token and returns the first failure.
+// /quote and /settlement use it to price against tokens /verify would accept.
+func (s *Service) checkToken(ctx context.Context, verifier TokenVerifier, name, uri string) (TokenCheck, string) {
+	check := TokenCheck{Role: name, URI: uri}
+	if strings.TrimSpace(uri) == "" {
+		return check, "missing_" + name
+	}
+	if verifier == nil {
+		return check, "metadata_missing:" + uri
+	}
+	if reason := inspectToken(s.clock.Now(), s.skew, verifier, &check); reason != "" {
+		return check, reason
+	}
+	for _, role := range tokenRoles {
+		if role.name != name {
+			continue
+		}
+		err := role.verify(verifier, ctx, uri)
+		var terms *verifylib.CORTError
+		switch {
+		case errors.As(err, &terms) && len(terms.Violations) > 0:
+			return check, terms.Violations[0].Code + ":" + uri
+		case err != nil:
+			return check, "invalid_" + name
+		}
+	}
+	return check, ""
+}
+
+// inspectToken fills the type, hash, window and revocation fields of check
+// and returns its first failure: missing or undecodable metadata, then
+// revocation, then nbf, then exp. The window is widened by skew on both ends.
+func inspectToken(now time.Time, skew time.Duration, provider TokenVerifier, check *TokenCheck) string {
+	uri := check.URI
+	payload, ok := provider.Token(uri)
+	if !ok || len(payload) == 0 {
+		check.Window, check.Revocation = CheckFail, CheckFail
+		return fmt.Sprintf("metadata_missing:%s", uri)
+	}
+	var meta struct {
+		Type      string `json:"type"`
+		NotBefore string `json:"nbf"`
+		Expires   string `json:"exp"`
+		Revoked   bool   `json:"revoked"`
+	}
+	if err := json.Unmarshal(payload, &meta); err != nil {
+		check.Window = CheckFail
+		return fmt.Sprintf("metadata_invalid:%s", uri)
+	}
+	check.Type = meta.Type
+	if digest, err := verifylib.CanonicalDigest(payload); err == nil {
+		check.Hash = digest
+	}
+	reason := ""
+	check.Revocation = CheckPass
+	if meta.Revoked {
+		check.Revocation = CheckFail
+		reason = fmt.Sprintf("token_revoked:%s", uri)
+	}
+	check.Window = CheckPass
+	windowReason := ""
+	if meta.NotBefore != "" {
+		nbf, err := time.Parse(time.RFC3339, meta.NotBefore)
+		switch {
+		case err != nil:
+			windowReason = fmt.Sprintf("invalid_nbf:%s", uri)
+		case now.Add(skew).Before(nbf):
+			windowReason = fmt.Sprintf("token_not_yet_valid:%s", uri)
+		}
+	}
+	if windowReason == "" && meta.Expires != "" {
+		exp, err := time.Parse(time.RFC3339, meta.Expires)
+		switch {
+		case err != nil:
+			windowReason = fmt.Sprintf("invalid_exp:%s", uri)
+		case now.Add(-skew).After(exp):
+			windowReason = fmt.Sprintf("token_expired:%s", uri)
+		}
+	}
+	if windowReason != "" {
+		check.Window = CheckFail
+		if reason == "" {
+			reason = windowReason
+		}
+	}
+	return reason
+}
@@ -0,0 +1,284 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kevin-biot/rtgf/rtgf-registry/internal/problem"
+)
+
+// SettlementRequest asks how a transaction settles under a CORT/PSRT pair.
+type SettlementRequest struct {
+	CORT string `json:"cort"`
+	PSRT string `json:"psrt"`
+	// Amount is a decimal string in major units with at most the currency's
+	// minor-unit count of fractional digits.
+	Amount string `json:"amount"`
+	// Currency sets the minor unit; it defaults to the currency of the RRMT
+	// the CORT references and must match it when both are known.
+	Currency string `json:"currency,omitempty"`
+	// AuthorizedAt (RFC 3339) starts the capture window; it defaults to now.
+	AuthorizedAt string `json:"authorized_at,omitempty"`
+}
+
+// SettlementPlan distributes a transaction amount: the PSRT dispute reserve
+// is held back first and the remainder is paid out per CORT split. Shares
+// and the reserve always sum to Amount exactly.
+type SettlementPlan struct {
+	CORT          string            `json:"cort"`
+	CORTHash      string            `json:"cort_hash"`
+	PSRT          string            `json:"psrt"`
+	PSRTHash      string            `json:"psrt_hash"`
+	RevEpoch      uint64            `json:"revEpoch"`
+	Currency      string            `json:"currency"`
+	Amount        string            `json:"amount"`
+	Reserve       SettlementReserve `json:"reserve"`
+	Distributable string            `json:"distributable"`
+	Shares        []SettlementShare `json:"shares"`
+	Payout        SettlementPayout  `json:"payout"`
+	Capture       SettlementCapture `json:"capture"`
+}
+
+// SettlementReserve is the dispute holdback kept by the PSRT acquirer.
+type SettlementReserve struct {
+	Holder string `json:"holder,omitempty"`
+	Pct    string `json:"pct"`
+	Amount string `json:"amount"`
+}
+
+// SettlementShare is one CORT party's payout.
+type SettlementShare struct {
+	Party  string `json:"party"`
+	Role   string `json:"role,omitempty"`
+	Pct    string `json:"pct"`
+	Amount string `json:"amount"`
+}
+
+// SettlementPayout echoes the CORT payout terms.
+type SettlementPayout struct {
+	Mode   string `json:"mode,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// SettlementCapture is the PSRT capture window. Deadline is AuthorizedAt plus
+// window_sec, omitted when the PSRT sets no window.
+type SettlementCapture struct {
+	Mode         string `json:"mode"`
+	WindowSec    *int64 `json:"window_sec,omitempty"`
+	AuthorizedAt string `json:"authorized_at"`
+	Deadline     string `json:"deadline,omitempty"`
+}
+
+type cortSettlement struct {
+	Parties []struct {
+		ID   string `json:"id"`
+		Role string `json:"role"`
+	} `json:"parties"`
+	References struct {
+		RRMT string `json:"rrmt"`
+	} `json:"references"`
+	Splits []struct {
+		Party string      `json:"party"`
+		Pct   json.Number `json:"pct"`
+	} `json:"splits"`
+	Payout SettlementPayout `json:"payout"`
+}
+
+type psrtSettlement struct {
+	Acquirer string `json:"acquirer"`
+	Capture  struct {
+		Mode      string       `json:"mode"`
+		WindowSec *json.Number `json:"window_sec"`
+	} `json:"capture"`
+	Dispute struct {
+		ReservePct json.Number `json:"reserve_pct"`
+	} `json:"dispute"`
+}
+
+// HandleSettlement computes the settlement plan for a verified CORT/PSRT pair.
+func (s *Service) HandleSettlement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		problem.Write(w, r, problem.MethodNotAllowed, "")
+		return
+	}
+	defer r.Body.Close()
+	var req SettlementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, problem.InvalidRequest, "settlement request must be a JSON object")
+		return
+	}
+	revEpoch := s.revEpoch.Load()
+	plan, reason := s.settlement(r.Context(), s.current().Verifier, req)
+	if reason != "" {
+		problem.New(problemForReason(reason), reason).
+			With("reason", reason).
+			With("revEpoch", revEpoch).
+			Write(w, r)
+		return
+	}
+	plan.RevEpoch = revEpoch
+	respondJSON(w, plan)
+}
+
+// settlement checks the CORT, the PSRT and the RRMT the CORT references as
+// /verify would, then holds back the PSRT dispute reserve (reserve_pct is a
+// fraction, like split pct: 0.05 keeps 5%), rounded half away from zero to
+// the minor unit, and splits the remainder by CORT split pct normalised to
+// their sum. Shares are floored to the minor unit and the leftover units go
+// one each to the largest remainders, ties to the earlier split, so the plan
+// reconciles to the cent and is the same on every call.
+func (s *Service) settlement(ctx context.Context, verifier TokenVerifier, req SettlementRequest) (SettlementPlan, string) {
+	cortCheck, reason := s.checkToken(ctx, verifier, "cort", req.CORT)
+	if reason != "" {
+		return SettlementPlan{}, reason
+	}
+	psrtCheck, reason := s.checkToken(ctx, verifier, "psrt", req.PSRT)
+	if reason != "" {
+		return SettlementPlan{}, reason
+	}
+	var cort cortSettlement
+	if err := decodeToken(verifier, req.CORT, &cort); err != nil {
+		return SettlementPlan{}, err.Error()
+	}
+	var psrt psrtSettlement
+	if err := decodeToken(verifier, req.PSRT, &psrt); err != nil {
+		return SettlementPlan{}, err.Error()
+	}
+	roles := make(map[string]string, len(cort.Parties))
+	for _, party := range cort.Parties {
+		roles[party.ID] = party.Role
+	}
+	if _, ok := roles[psrt.Acquirer]; psrt.Acquirer != "" && !ok {
+		return SettlementPlan{}, "context_mismatch:acquirer"
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if uri := cort.References.RRMT; uri != "" {
+		if _, reason := s.checkToken(ctx, verifier, "rrmt", uri); reason != "" {
+			return SettlementPlan{}, reason
+		}
+		var rrmt rrmtContext
+		if err := decodeToken(verifier, uri, &rrmt); err != nil {
+			return SettlementPlan{}, err.Error()
+		}
+		if rrmt.Currency != "" {
+			if currency != "" && !strings.EqualFold(currency, rrmt.Currency) {
+				return SettlementPlan{}, "context_mismatch:currency"
+			}
+			currency = strings.ToUpper(rrmt.Currency)
+		}
+	}
+	if currency == "" {
+		return SettlementPlan{}, "invalid_settlement:currency"
+	}
+	places := minorUnits(currency)
+	amount, ok := parseAmount(req.Amount, places)
+	if !ok {
+		return SettlementPlan{}, "invalid_settlement:amount"
+	}
+	authorized := s.clock.Now().UTC()
+	if req.AuthorizedAt != "" {
+		at, err := time.Parse(time.RFC3339, req.AuthorizedAt)
+		if err != nil {
+			return SettlementPlan{}, "invalid_settlement:authorized_at"
+		}
+		authorized = at.UTC()
+	}
+
+	reservePct := new(big.Rat)
+	if psrt.Dispute.ReservePct != "" {
+		pct, ok := new(big.Rat).SetString(psrt.Dispute.ReservePct.String())
+		if !ok || pct.Sign() < 0 || pct.Cmp(big.NewRat(1, 1)) > 0 {
+			return SettlementPlan{}, "reserve_invalid:" + req.PSRT
+		}
+		reservePct = pct
+	}
+	reserve := roundMinor(new(big.Rat).Mul(amount, reservePct), places)
+	distributable := new(big.Rat).Sub(amount, reserve)
+
+	pcts := make([]*big.Rat, len(cort.Splits))
+	total := new(big.Rat)
+	for i, split := range cort.Splits {
+		pct, ok := new(big.Rat).SetString(split.Pct.String())
+		if !ok || pct.Sign() < 0 {
+			return SettlementPlan{}, "cort_split_invalid:" + req.CORT
+		}
+		pcts[i] = pct
+		total.Add(total, pct)
+	}
+	units, ok := allocateMinor(distributable, pcts, total, places)
+	if !ok {
+		return SettlementPlan{}, "cort_split_total:" + req.CORT
+	}
+
+	plan := SettlementPlan{
+		CORT: req.CORT, CORTHash: cortCheck.Hash,
+		PSRT: req.PSRT, PSRTHash: psrtCheck.Hash,
+		Currency: currency,
+		Amount:   formatMinor(amount, places),
+		Reserve: SettlementReserve{
+			Holder: psrt.Acquirer, Pct: rateString(psrt.Dispute.ReservePct), Amount: formatMinor(reserve, places),
+		},
+		Distributable: formatMinor(distributable, places),
+		Shares:        make([]SettlementShare, 0, len(cort.Splits)),
+		Payout:        cort.Payout,
+		Capture:       SettlementCapture{Mode: psrt.Capture.Mode, AuthorizedAt: authorized.Format(time.RFC3339)},
+	}
+	for i, split := range cort.Splits {
+		plan.Shares = append(plan.Shares, SettlementShare{
+			Party: split.Party, Role: roles[split.Party], Pct: split.Pct.String(), Amount: formatMinor(units[i], places),
+		})
+	}
+	if psrt.Capture.WindowSec != nil {
+		window, err := psrt.Capture.WindowSec.Int64()
+		if err != nil || window < 0 {
+			return SettlementPlan{}, "capture_invalid:" + req.PSRT
+		}
+		plan.Capture.WindowSec = &window
+		plan.Capture.Deadline = authorized.Add(time.Duration(window) * time.Second).Format(time.RFC3339)
+	}
+	return plan, ""
+}
+
+// allocateMinor splits amount, a whole number of minor units, in proportion
+// to weights with the largest remainder method. The returned shares sum to
+// amount exactly. It reports false when the weights are not positive in sum.
+func allocateMinor(amount *big.Rat, weights []*big.Rat, total *big.Rat, places int) ([]*big.Rat, bool) {
+	if total.Sign() <= 0 {
+		return nil, false
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil))
+	units := new(big.Rat).Mul(amount, scale).Num()
+	type part struct {
+		index     int
+		floor     *big.Int
+		remainder *big.Rat
+	}
+	parts := make([]part, len(weights))
+	left := new(big.Int).Set(units)
+	for i, weight := range weights {
+		exact := new(big.Rat).Mul(new(big.Rat).SetInt(units), weight)
+		exact.Quo(exact, total)
+		floor := new(big.Int).Quo(exact.Num(), exact.Denom())
+		remainder := new(big.Rat).Sub(exact, new(big.Rat).SetInt(floor))
+		parts[i] = part{i, floor, remainder}
+		left.Sub(left, floor)
+	}
+	order := make([]part, len(parts))
+	copy(order, parts)
+	sort.SliceStable(order, func(a, b int) bool { return order[a].remainder.Cmp(order[b].remainder) > 0 })
+	for i := 0; left.Sign() > 0 && len(order) > 0; i = (i + 1) % len(order) {
+		order[i].floor.Add(order[i].floor, big.NewInt(1))
+		left.Sub(left, big.NewInt(1))
+	}
+	shares := make([]*big.Rat, len(parts))
+	for _, p := range parts {
+		shares[p.index] = new(big.Rat).Quo(new(big.Rat).SetInt(p.floor), scale)
+	}
+	return shares, true
+}
@@ -0,0 +1,394 @@
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CatalogEntry describes a published token and the fixture holding its payload.
+type CatalogEntry struct {
+	TokenInfo
+	Slug string `json:"slug,omitempty"`
+	File string `json:"file"`
+}
+
+// Catalog is the single source of token metadata shared by the registry API
+// and the static verifier.
+type Catalog []CatalogEntry
+
+// Manifest is the on-disk catalog format accepted by LoadManifest.
+type Manifest struct {
+	Tokens Catalog `json:"tokens"`
+}
+
+// DefaultCatalog enumerates the sandbox fixtures bundled with RTGF docs.
+var DefaultCatalog = Catalog{
+	{
+		TokenInfo: TokenInfo{
+			URI:       "urn:lane2:token:RRMT:EU:PSD3:3.2",
+			Type:      "RRMT",
+			Version:   "2025.10",
+			IssuedAt:  "2025-10-01T00:00:00Z",
+			NotBefore: "2025-10-01T00:00:00Z",
+			ExpiresAt: "2026-10-01T00:00:00Z",
+			Hash:      "sha256:8e5fd1c7c2bf088b69a7416c65c8ed5050d25ea06053917efa4819066b5326aa",
+		},
+		Slug: "eu-psd3-2025",
+		File: "rrmt-eu-psd3-2025.json",
+	},
+	{
+		TokenInfo: TokenInfo{
+			URI:       "urn:lane2:token:RMT:EU:PSD3:3.2",
+			Type:      "RMT",
+			Version:   "2025.10",
+			IssuedAt:  "2025-10-01T00:00:00Z",
+			NotBefore: "2025-10-01T00:00:00Z",
+			ExpiresAt: "2026-10-01T00:00:00Z",
+			Hash:      "sha256:46f5c37219366082556b57a830085c3ca4ac17ca16cd2b517793308176287141",
+		},
+		Slug: "eu-psd3-2025",
+		File: "rmt-eu-psd3-2025.json",
+	},
+	{
+		TokenInfo: TokenInfo{
+			URI:       "urn:lane2:token:RMT:SG:PSD3:3.2",
+			Type:      "RMT",
+			Version:   "2025.10",
+			IssuedAt:  "2025-10-01T00:00:00Z",
+			NotBefore: "2025-10-01T00:00:00Z",
+			ExpiresAt: "2026-10-01T00:00:00Z",
+			Hash:      "sha256:d098e7f4855b4308f89f4a132abf6eec4ca0802feba68f76b7c8a4351df0a104",
+		},
+		Slug: "sg-psd3-2025",
+		File: "rmt-sg-psd3-2025.json",
+	},
+	{
+		TokenInfo: TokenInfo{
+			URI:       "urn:lane2:token:IMT:EU:SG:2025",
+			Type:      "IMT",
+			Version:   "2025.10",
+			IssuedAt:  "2025-10-01T00:00:00Z",
+			NotBefore: "2025-10-01T00:00:00Z",
+			ExpiresAt: "2026-10-01T00:00:00Z",
+			Hash:      "sha256:0f9b421cb71ef54914d2c21500df2f72a34be816fea2bbeb52637a3edc77885f",
+		},
+		Slug: "eu-sg-2025",
+		File: "imt-eu-sg-2025.json",
+	},
+	{
+		TokenInfo: TokenInfo{
+			URI:       "urn:lane2:token:CORT:VODAFONE.VISA:2025",
+			Type:      "CORT",
+			Version:   "2025-Q4",
+			IssuedAt:  "2025-10-01T00:00:00Z",
+			NotBefore: "2025-10-01T00:00:00Z",
+			ExpiresAt: "2026-04-01T00:00:00Z",
+			Hash:      "sha256:9476a080f2075d55d821c3bc18e72e01c69365919a87d24275c637bcf034c0e3",
+		},
+		Slug: "vodafone-visa-2025",
+		File: "cort-vodafone-visa-2025.json",
+	},
+	{
+		TokenInfo: TokenInfo{
+			URI:       "urn:lane2:token:PSRT:VISA:ACQ-123",
+			Type:      "PSRT",
+			Version:   "2025-01",
+			IssuedAt:  "2025-10-01T00:00:00Z",
+			NotBefore: "2025-10-01T00:00:00Z",
+			ExpiresAt: "2026-01-01T00:00:00Z",
+			Hash:      "sha256:b12b01cfb79326d0314d06c3d40deb27359e9ff3aa24e4a4dade7a275eb18686",
+		},
+		Slug: "visa-acq-123",
+		File: "psrt-visa-acq-123.json",
+	},
+}
+
+// FileMap returns the URI to fixture mapping for NewStaticVerifier, holding
+// the version of each URI that is current at now (see CurrentVersion).
+func (c Catalog) FileMap(now time.Time) FileMap {
+	files := make(FileMap, len(c))
+	for _, entry := range c.Current(now) {
+		files[entry.URI] = entry.File
+	}
+	return files
+}
+
+// Lookup returns the version of uri that is current at now.
+func (c Catalog) Lookup(uri string, now time.Time) (CatalogEntry, bool) {
+	var versions Catalog
+	for _, entry := range c {
+		if entry.URI == uri {
+			versions = append(versions, entry)
+		}
+	}
+	if len(versions) == 0 {
+		return CatalogEntry{}, false
+	}
+	return versions[versions.currentIndex(now)], true
+}
+
+// Current returns the current version of every URI in the catalog, in the
+// order each URI first appears.
+func (c Catalog) Current(now time.Time) Catalog {
+	groups := make(map[string]Catalog, len(c))
+	var order []string
+	for _, entry := range c {
+		if _, seen := groups[entry.URI]; !seen {
+			order = append(order, entry.URI)
+		}
+		groups[entry.URI] = append(groups[entry.URI], entry)
+	}
+	out := make(Catalog, 0, len(order))
+	for _, uri := range order {
+		versions := groups[uri]
+		out = append(out, versions[versions.currentIndex(now)])
+	}
+	return out
+}
+
+func (c Catalog) currentIndex(now time.Time) int {
+	infos := make([]TokenInfo, len(c))
+	for i, entry := range c {
+		infos[i] = entry.TokenInfo
+	}
+	return CurrentVersion(infos, now)
+}
+
+// CurrentVersion returns the index of the version to serve at now: the newest
+// non-revoked version whose validity window holds now, falling back to the
+// newest version. The registry resolves unpinned lookups with the same rule,
+// so /tokens and the verifier agree on which version a URI means. versions
+// must not be empty.
+func CurrentVersion(versions []TokenInfo, now time.Time) int {
+	best, newest := -1, 0
+	for i, info := range versions {
+		if NewerThan(info, versions[newest]) {
+			newest = i
+		}
+		if info.Revoked || !WithinWindow(info, now) {
+			continue
+		}
+		if best < 0 || NewerThan(info, versions[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return newest
+	}
+	return best
+}
+
+// WithinWindow reports whether now falls inside the nbf/exp window of info.
+// A missing bound is open; an unparseable one never matches.
+func WithinWindow(info TokenInfo, now time.Time) bool {
+	if info.NotBefore != "" {
+		nbf, err := time.Parse(time.RFC3339, info.NotBefore)
+		if err != nil || now.Before(nbf) {
+			return false
+		}
+	}
+	if info.ExpiresAt != "" {
+		exp, err := time.Parse(time.RFC3339, info.ExpiresAt)
+		if err != nil || now.After(exp) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewerThan orders versions by nbf, then issued_at, then version, falling
+// back to the URI so the choice is deterministic.
+func NewerThan(a, b TokenInfo) bool {
+	if a.NotBefore != b.NotBefore {
+		return laterTimestamp(a.NotBefore, b.NotBefore)
+	}
+	if a.IssuedAt != b.IssuedAt {
+		return laterTimestamp(a.IssuedAt, b.IssuedAt)
+	}
+	if a.Version != b.Version {
+		return a.Version > b.Version
+	}
+	return a.URI > b.URI
+}
+
+func laterTimestamp(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return ta.After(tb)
+}
+
+// versionKey identifies one published version of a token URI.
+func (e CatalogEntry) versionKey() string {
+	return e.URI + "@" + e.Version
+}
+
+// LoadManifest reads a JSON manifest of catalog entries. Fields omitted from an
+// entry are derived from the referenced token file, resolved relative to baseDir.
+func LoadManifest(fsys fs.FS, manifestPath, baseDir string) (Catalog, error) {
+	data, err := fs.ReadFile(fsys, manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest %s: %w", manifestPath, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest %s: %w", manifestPath, err)
+	}
+	if len(manifest.Tokens) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no tokens", manifestPath)
+	}
+	catalog := make(Catalog, 0, len(manifest.Tokens))
+	seen := make(map[string]struct{}, len(manifest.Tokens))
+	for i, declared := range manifest.Tokens {
+		if declared.File == "" {
+			return nil, fmt.Errorf("manifest entry %d: file is required", i)
+		}
+		derived, err := DescribeToken(fsys, path.Join(baseDir, declared.File))
+		if err != nil {
+			return nil, fmt.Errorf("manifest entry %d: %w", i, err)
+		}
+		entry := mergeEntry(declared, derived)
+		if entry.URI == "" {
+			return nil, fmt.Errorf("manifest entry %d: uri is required when %s declares none", i, declared.File)
+		}
+		if _, dup := seen[entry.versionKey()]; dup {
+			return nil, fmt.Errorf("manifest entry %d: duplicate token %s", i, entry.versionKey())
+		}
+		seen[entry.versionKey()] = struct{}{}
+		catalog = append(catalog, entry)
+	}
+	return catalog, nil
+}
+
+// ScanCatalog derives catalog entries from every token JSON file in dir. Files
+// without a recognised `type` discriminator (e.g. jwks.json) are skipped, and
+// every token must declare its canonical URI.
+func ScanCatalog(fsys fs.FS, dir string) (Catalog, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", dir, err)
+	}
+	var catalog Catalog
+	seen := make(map[string]string)
+	for _, dirEntry := range entries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		entry, err := DescribeToken(fsys, path.Join(dir, dirEntry.Name()))
+		if errors.Is(err, errNotToken) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.URI == "" {
+			return nil, fmt.Errorf("scan %s: token %s declares no uri", dir, entry.File)
+		}
+		if other, dup := seen[entry.versionKey()]; dup {
+			return nil, fmt.Errorf("scan %s: token %s declared by %s and %s", dir, entry.versionKey(), other, entry.File)
+		}
+		seen[entry.versionKey()] = entry.File
+		catalog = append(catalog, entry)
+	}
+	if len(catalog) == 0 {
+		return nil, fmt.Errorf("scan %s: no token fixtures found", dir)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		if catalog[i].URI != catalog[j].URI {
+			return catalog[i].URI < catalog[j].URI
+		}
+		return catalog[i].NotBefore < catalog[j].NotBefore
+	})
+	return catalog, nil
+}
+
+var errNotToken = errors.New("not a token payload")
+
+// DescribeToken derives a catalog entry from the token stored at name. The
+// URI comes from the payload's `uri` (or `rmt_id`/`imt_id`) member and is
+// left empty when the payload declares none; the hash is its CanonicalDigest.
+func DescribeToken(fsys fs.FS, name string) (CatalogEntry, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return CatalogEntry{}, fmt.Errorf("read token %s: %w", name, err)
+	}
+	var payload struct {
+		TokenInfo
+		RMTID string `json:"rmt_id"`
+		IMTID string `json:"imt_id"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return CatalogEntry{}, fmt.Errorf("decode token %s: %w", name, err)
+	}
+	tokenType := strings.ToUpper(payload.Type)
+	if !knownType(tokenType) {
+		return CatalogEntry{}, fmt.Errorf("%s: %w", name, errNotToken)
+	}
+	base := path.Base(name)
+	slug := strings.TrimSuffix(base, ".json")
+	slug = strings.TrimPrefix(slug, strings.ToLower(tokenType)+"-")
+
+	entry := CatalogEntry{TokenInfo: payload.TokenInfo, Slug: slug, File: base}
+	entry.Type = tokenType
+	switch {
+	case entry.URI != "":
+	case payload.RMTID != "":
+		entry.URI = payload.RMTID
+	case payload.IMTID != "":
+		entry.URI = payload.IMTID
+	}
+	digest, err := CanonicalDigest(data)
+	if err != nil {
+		return CatalogEntry{}, fmt.Errorf("digest token %s: %w", name, err)
+	}
+	entry.Hash = digest
+	return entry, nil
+}
+
+func knownType(tokenType string) bool {
+	switch tokenType {
+	case "RMT", "IMT", "RRMT", "CORT", "PSRT", "AMLS", "AMLV":
+		return true
+	default:
+		return false
+	}
+}
+
+// mergeEntry keeps declared manifest values and fills gaps from the payload.
+func mergeEntry(declared, derived CatalogEntry) CatalogEntry {
+	out := declared
+	if out.URI == "" {
+		out.URI = derived.URI
+	}
+	if out.Type == "" {
+		out.Type = derived.Type
+	}
+	if out.Slug == "" {
+		out.Slug = derived.Slug
+	}
+	if out.Version == "" {
+		out.Version = derived.Version
+	}
+	if out.IssuedAt == "" {
+		out.IssuedAt = derived.IssuedAt
+	}
+	if out.NotBefore == "" {
+		out.NotBefore = derived.NotBefore
+	}
+	if out.ExpiresAt == "" {
+		out.ExpiresAt = derived.ExpiresAt
+	}
+	if out.Hash == "" {
+		out.Hash = derived.Hash
+	}
+	out.Revoked = out.Revoked || derived.Revoked
+	return out
+}
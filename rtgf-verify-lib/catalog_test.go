@@ -0,0 +1,185 @@
+package verify
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func catalogFS() fstest.MapFS {
+	return fstest.MapFS{
+		"rrmt-eu-psd3-2025.json": {Data: []byte(`{"type":"RRMT","uri":"urn:lane2:token:RRMT:EU:PSD3:3.2",` + testRRMTClaims + `,"version":"2025.10","nbf":"2025-10-01T00:00:00Z","exp":"2026-10-01T00:00:00Z"}`)},
+		"imt-eu-sg-2025.json": {Data: []byte(`{"type":"IMT","imt_id":"urn:lane2:token:IMT:EU:SG:2025","corridor":"EU-SG","domain":"payments_psd3",` +
+			`"effective_date":"2025-10-01T00:00:00Z","expires_at":"2026-10-01T00:00:00Z","policy_snapshot_hash":"sha256:test","version":"2025.10"}`)},
+		"psrt-visa.json": {Data: []byte(`{"type":"PSRT","uri":"urn:lane2:token:PSRT:VISA:ACQ-123",` + testPSRTClaims + `,"version":"2025-01"}`)},
+		"jwks.json":      {Data: []byte(`{"keys":[]}`)},
+		"notes.txt":      {Data: []byte(`ignored`)},
+	}
+}
+
+func TestScanCatalog(t *testing.T) {
+	catalog, err := ScanCatalog(catalogFS(), ".")
+	if err != nil {
+		t.Fatalf("ScanCatalog: %v", err)
+	}
+	if len(catalog) != 3 {
+		t.Fatalf("expected 3 entries, got %d: %+v", len(catalog), catalog)
+	}
+	rrmt, ok := catalog.Lookup("urn:lane2:token:RRMT:EU:PSD3:3.2", time.Time{})
+	if !ok {
+		t.Fatalf("expected RRMT entry from payload uri")
+	}
+	if rrmt.Slug != "eu-psd3-2025" || rrmt.File != "rrmt-eu-psd3-2025.json" || rrmt.Version != "2025.10" || rrmt.ExpiresAt == "" {
+		t.Fatalf("unexpected RRMT entry %+v", rrmt)
+	}
+	if !strings.HasPrefix(rrmt.Hash, "sha256:") || len(rrmt.Hash) != 71 {
+		t.Fatalf("expected computed sha256 hash, got %q", rrmt.Hash)
+	}
+	if _, ok := catalog.Lookup("urn:lane2:token:IMT:EU:SG:2025", time.Time{}); !ok {
+		t.Fatalf("expected IMT entry from imt_id")
+	}
+	if _, ok := catalog.Lookup("urn:lane2:token:PSRT:VISA:ACQ-123", time.Time{}); !ok {
+		t.Fatalf("expected PSRT entry from payload uri, got %+v", catalog)
+	}
+}
+
+func TestScanCatalogRequiresURI(t *testing.T) {
+	fsys := catalogFS()
+	fsys["cort.json"] = &fstest.MapFile{Data: []byte(`{"type":"CORT",` + testCORTTerms + `}`)}
+	if _, err := ScanCatalog(fsys, "."); err == nil || !strings.Contains(err.Error(), "cort.json declares no uri") {
+		t.Fatalf("expected missing uri error, got %v", err)
+	}
+	fsys["manifest.json"] = &fstest.MapFile{Data: []byte(`{"tokens":[{"file":"cort.json"}]}`)}
+	if _, err := LoadManifest(fsys, "manifest.json", "."); err == nil || !strings.Contains(err.Error(), "uri is required") {
+		t.Fatalf("expected manifest uri error, got %v", err)
+	}
+}
+
+func TestScanBundledFixtures(t *testing.T) {
+	fsys := os.DirFS(filepath.Join("..", "registry", "static", "tokens"))
+	catalog, err := ScanCatalog(fsys, ".")
+	if err != nil {
+		t.Fatalf("ScanCatalog: %v", err)
+	}
+	for _, want := range DefaultCatalog {
+		got, ok := catalog.Lookup(want.URI, time.Time{})
+		if !ok || got.Type != want.Type || got.Version != want.Version || got.File != want.File || got.Hash != want.Hash {
+			t.Fatalf("expected scanned %s to match the default catalog, got %+v", want.URI, got)
+		}
+	}
+	verifier, err := NewStaticVerifierFromCatalog(fsys, ".", catalog, time.Time{})
+	if err != nil {
+		t.Fatalf("NewStaticVerifierFromCatalog: %v", err)
+	}
+	var cort struct {
+		References map[string]string `json:"references"`
+	}
+	payload, _ := verifier.Token("urn:lane2:token:CORT:VODAFONE.VISA:2025")
+	if err := json.Unmarshal(payload, &cort); err != nil || len(cort.References) == 0 {
+		t.Fatalf("decode CORT references: %v", err)
+	}
+	for name, uri := range cort.References {
+		if _, ok := verifier.Token(uri); !ok {
+			t.Fatalf("CORT references.%s %s is missing from the scanned catalog", name, uri)
+		}
+	}
+}
+
+func TestScanCatalogDuplicateURI(t *testing.T) {
+	fsys := catalogFS()
+	fsys["rrmt-copy.json"] = fsys["rrmt-eu-psd3-2025.json"]
+	if _, err := ScanCatalog(fsys, "."); err == nil || !strings.Contains(err.Error(), "declared by") {
+		t.Fatalf("expected duplicate uri error, got %v", err)
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	fsys := catalogFS()
+	fsys["manifest.json"] = &fstest.MapFile{Data: []byte(`{"tokens":[
+		{"uri":"urn:lane2:token:RMT:EU:PSD3:3.2","type":"RMT","file":"rrmt-eu-psd3-2025.json"},
+		{"file":"imt-eu-sg-2025.json","version":"pinned"}
+	]}`)}
+	catalog, err := LoadManifest(fsys, "manifest.json", ".")
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	rmt, ok := catalog.Lookup("urn:lane2:token:RMT:EU:PSD3:3.2", time.Time{})
+	if !ok || rmt.Type != "RMT" || rmt.Version != "2025.10" || rmt.Hash == "" {
+		t.Fatalf("expected declared uri/type with derived metadata, got %+v", rmt)
+	}
+	imt, ok := catalog.Lookup("urn:lane2:token:IMT:EU:SG:2025", time.Time{})
+	if !ok || imt.Version != "pinned" {
+		t.Fatalf("expected manifest version to win, got %+v", imt)
+	}
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	cases := map[string]string{
+		`{"tokens":[]}`:                        "lists no tokens",
+		`{"tokens":[{"uri":"urn:x"}]}`:         "file is required",
+		`{"tokens":[{"file":"missing.json"}]}`: "read token",
+		`{"tokens":[{"file":"jwks.json"}]}`:    "not a token payload",
+		`{"tokens":`:                           "decode manifest",
+	}
+	for manifest, want := range cases {
+		fsys := catalogFS()
+		fsys["manifest.json"] = &fstest.MapFile{Data: []byte(manifest)}
+		if _, err := LoadManifest(fsys, "manifest.json", "."); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("manifest %s: expected %q, got %v", manifest, want, err)
+		}
+	}
+}
+
+func TestNewStaticVerifierFromCatalog(t *testing.T) {
+	fsys := catalogFS()
+	catalog, err := ScanCatalog(fsys, ".")
+	if err != nil {
+		t.Fatalf("ScanCatalog: %v", err)
+	}
+	verifier, err := NewStaticVerifierFromCatalog(fsys, ".", catalog, time.Time{})
+	if err != nil {
+		t.Fatalf("NewStaticVerifierFromCatalog: %v", err)
+	}
+	for _, entry := range catalog {
+		info, ok := verifier.Metadata(entry.URI)
+		if !ok || info.Hash != entry.Hash {
+			t.Fatalf("expected catalog hash for %s, got %+v", entry.URI, info)
+		}
+	}
+	if _, err := NewStaticVerifierFromCatalog(fsys, ".", nil, time.Time{}); err == nil {
+		t.Fatalf("expected error for empty catalog")
+	}
+}
+
+func TestCatalogCurrentVersion(t *testing.T) {
+	const uri = "urn:lane2:token:RRMT:EU:PSD3:3.2"
+	catalog := Catalog{
+		{TokenInfo: TokenInfo{URI: uri, Version: "2025.10", NotBefore: "2025-10-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z"}, File: "v2.json"},
+		{TokenInfo: TokenInfo{URI: uri, Version: "2026.01", NotBefore: "2026-01-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z", Revoked: true}, File: "v3.json"},
+		{TokenInfo: TokenInfo{URI: uri, Version: "2025.01", NotBefore: "2025-01-01T00:00:00Z", ExpiresAt: "2027-01-01T00:00:00Z"}, File: "v1.json"},
+	}
+	cases := []struct {
+		now  time.Time
+		file string
+	}{
+		{time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), "v2.json"},
+		{time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), "v1.json"},
+		{time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), "v3.json"},
+	}
+	for _, tc := range cases {
+		entry, ok := catalog.Lookup(uri, tc.now)
+		if !ok || entry.File != tc.file {
+			t.Fatalf("%s: expected %s, got %+v", tc.now, tc.file, entry)
+		}
+		if files := catalog.FileMap(tc.now); len(files) != 1 || files[uri] != tc.file {
+			t.Fatalf("%s: expected file map to select %s, got %v", tc.now, tc.file, files)
+		}
+	}
+	if _, ok := catalog.Lookup("urn:missing", time.Time{}); ok {
+		t.Fatalf("expected unknown uri to miss")
+	}
+}
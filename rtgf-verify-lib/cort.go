@@ -0,0 +1,160 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// CORT violation codes reported by ValidateCORT.
+const (
+	CORTMalformed     = "cort_malformed"
+	CORTPartyInvalid  = "cort_party_invalid"
+	CORTMissingRole   = "cort_missing_role"
+	CORTSplitInvalid  = "cort_split_invalid"
+	CORTSplitParty    = "cort_split_party"
+	CORTSplitTotal    = "cort_split_total"
+	CORTFXInvalid     = "cort_fx_invalid"
+	CORTPayoutInvalid = "cort_payout_invalid"
+)
+
+// CORTRequiredRoles lists the party roles every CORT must name.
+var CORTRequiredRoles = []string{"merchant_of_record", "scheme"}
+
+// CORTPayoutModes enumerates the settlement modes allowed in payout.mode.
+var CORTPayoutModes = []string{"instant", "t+1", "t+7"}
+
+// splitTolerance bounds how far split percentages may sum from exactly 1.
+var splitTolerance = big.NewRat(1, 1_000_000)
+
+// CORTViolation is one failed commercial-terms rule.
+type CORTViolation struct {
+	Code   string
+	Detail string
+}
+
+// CORTError lists every commercial-terms violation of a CORT, in rule order.
+type CORTError struct {
+	URI        string
+	Violations []CORTViolation
+}
+
+func (e *CORTError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.Code+": "+v.Detail)
+	}
+	return fmt.Sprintf("token %s has invalid commercial terms: %s", e.URI, strings.Join(parts, "; "))
+}
+
+type cortTerms struct {
+	Parties []struct {
+		ID   string `json:"id"`
+		Role string `json:"role"`
+	} `json:"parties"`
+	Splits []struct {
+		Party string      `json:"party"`
+		Pct   json.Number `json:"pct"`
+	} `json:"splits"`
+	FX *struct {
+		Enabled      *bool        `json:"enabled"`
+		Source       string       `json:"source"`
+		ToleranceBps *json.Number `json:"tolerance_bps"`
+	} `json:"fx"`
+	Payout *struct {
+		Mode   string `json:"mode"`
+		Scheme string `json:"scheme"`
+	} `json:"payout"`
+}
+
+// ValidateCORT checks a CORT's commercial terms: parties carry an id and a
+// role, ids are unique and CORTRequiredRoles are present; every split pays
+// a listed party a percentage in [0, 1] and the percentages sum to 1 within
+// one part per million; an fx block states whether it is enabled and, if so,
+// its rate source and a tolerance of 0-10000 bps; a payout block names a
+// CORTPayoutModes mode and a scheme. It returns nil when every rule holds.
+func ValidateCORT(payload []byte) []CORTViolation {
+	var terms cortTerms
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&terms); err != nil {
+		return []CORTViolation{{CORTMalformed, fmt.Sprintf("decode terms: %v", err)}}
+	}
+	var violations []CORTViolation
+	add := func(code, format string, args ...any) {
+		violations = append(violations, CORTViolation{code, fmt.Sprintf(format, args...)})
+	}
+
+	parties := make(map[string]bool, len(terms.Parties))
+	roles := make(map[string]bool, len(terms.Parties))
+	if len(terms.Parties) == 0 {
+		add(CORTPartyInvalid, "no parties")
+	}
+	for i, party := range terms.Parties {
+		switch {
+		case strings.TrimSpace(party.ID) == "" || strings.TrimSpace(party.Role) == "":
+			add(CORTPartyInvalid, "parties[%d] needs an id and a role", i)
+		case parties[party.ID]:
+			add(CORTPartyInvalid, "party %s listed twice", party.ID)
+		}
+		parties[party.ID] = true
+		roles[party.Role] = true
+	}
+	for _, role := range CORTRequiredRoles {
+		if !roles[role] {
+			add(CORTMissingRole, "no %s party", role)
+		}
+	}
+
+	total := new(big.Rat)
+	if len(terms.Splits) == 0 {
+		add(CORTSplitTotal, "no splits")
+	}
+	for i, split := range terms.Splits {
+		if pct, ok := new(big.Rat).SetString(split.Pct.String()); !ok {
+			add(CORTSplitInvalid, "splits[%d].pct %q is not a number", i, split.Pct)
+		} else {
+			if pct.Sign() < 0 || pct.Cmp(big.NewRat(1, 1)) > 0 {
+				add(CORTSplitInvalid, "splits[%d].pct %s is not a fraction in [0, 1]", i, split.Pct)
+			}
+			total.Add(total, pct)
+		}
+		if !parties[split.Party] {
+			add(CORTSplitParty, "splits[%d] pays %q, which is not a party", i, split.Party)
+		}
+	}
+	if len(terms.Splits) > 0 {
+		if diff := new(big.Rat).Sub(total, big.NewRat(1, 1)); new(big.Rat).Abs(diff).Cmp(splitTolerance) > 0 {
+			add(CORTSplitTotal, "splits sum to %s, not 1", total.FloatString(6))
+		}
+	}
+
+	if fx := terms.FX; fx != nil {
+		switch {
+		case fx.Enabled == nil:
+			add(CORTFXInvalid, "fx.enabled is required")
+		case *fx.Enabled && strings.TrimSpace(fx.Source) == "":
+			add(CORTFXInvalid, "enabled fx needs a source")
+		}
+		if fx.ToleranceBps != nil {
+			if bps, err := fx.ToleranceBps.Int64(); err != nil || bps < 0 || bps > 10000 {
+				add(CORTFXInvalid, "fx.tolerance_bps %q is not an integer in [0, 10000]", *fx.ToleranceBps)
+			}
+		}
+	}
+	if payout := terms.Payout; payout != nil {
+		valid := false
+		for _, mode := range CORTPayoutModes {
+			valid = valid || payout.Mode == mode
+		}
+		if !valid {
+			add(CORTPayoutInvalid, "payout.mode %q is not one of %s", payout.Mode, strings.Join(CORTPayoutModes, ", "))
+		}
+		if strings.TrimSpace(payout.Scheme) == "" {
+			add(CORTPayoutInvalid, "payout.scheme is required")
+		}
+	}
+	return violations
+}
@@ -0,0 +1,76 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testCORTTerms = `"parties":[{"id":"did:org:m","role":"merchant_of_record"},{"id":"did:org:s","role":"scheme"}],` +
+	`"references":{"rrmt":"urn:lane2:token:RRMT:EU:PSD3:3.2"},"splits":[{"party":"did:org:m","pct":0.95},{"party":"did:org:s","pct":0.05}]`
+
+func TestValidateCORT(t *testing.T) {
+	parties := `"parties":[{"id":"did:org:m","role":"merchant_of_record"},{"id":"did:org:s","role":"scheme"}]`
+	cases := map[string]struct {
+		terms string
+		want  []string
+	}{
+		"valid":          {testCORTTerms + `,"fx":{"enabled":true,"source":"ECB","tolerance_bps":25},"payout":{"mode":"instant","scheme":"SEPA_INSTANT"}`, nil},
+		"thirds":         {parties + `,"splits":[{"party":"did:org:m","pct":0.333333},{"party":"did:org:m","pct":0.333333},{"party":"did:org:s","pct":0.333334}]`, nil},
+		"over one":       {parties + `,"splits":[{"party":"did:org:m","pct":1.0},{"party":"did:org:s","pct":0.2}]`, []string{CORTSplitTotal}},
+		"under one":      {parties + `,"splits":[{"party":"did:org:m","pct":0.5}]`, []string{CORTSplitTotal}},
+		"no splits":      {parties, []string{CORTSplitTotal}},
+		"negative pct":   {parties + `,"splits":[{"party":"did:org:m","pct":1.1},{"party":"did:org:s","pct":-0.1}]`, []string{CORTSplitInvalid, CORTSplitInvalid}},
+		"missing pct":    {parties + `,"splits":[{"party":"did:org:m","pct":1},{"party":"did:org:s"}]`, []string{CORTSplitInvalid}},
+		"unknown payee":  {parties + `,"splits":[{"party":"did:org:m","pct":0.5},{"party":"did:org:x","pct":0.5}]`, []string{CORTSplitParty}},
+		"missing scheme": {`"parties":[{"id":"did:org:m","role":"merchant_of_record"}],"splits":[{"party":"did:org:m","pct":1}]`, []string{CORTMissingRole}},
+		"party no role":  {`"parties":[{"id":"did:org:m","role":"merchant_of_record"},{"id":"did:org:s","role":"scheme"},{"id":"did:org:x"}]` + `,"splits":[{"party":"did:org:m","pct":1}]`, []string{CORTPartyInvalid}},
+		"duplicate":      {`"parties":[{"id":"did:org:m","role":"merchant_of_record"},{"id":"did:org:m","role":"scheme"}],"splits":[{"party":"did:org:m","pct":1}]`, []string{CORTPartyInvalid}},
+		"fx no source":   {testCORTTerms + `,"fx":{"enabled":true}`, []string{CORTFXInvalid}},
+		"fx no enabled":  {testCORTTerms + `,"fx":{"source":"ECB"}`, []string{CORTFXInvalid}},
+		"fx bps":         {testCORTTerms + `,"fx":{"enabled":false,"tolerance_bps":2.5}`, []string{CORTFXInvalid}},
+		"payout mode":    {testCORTTerms + `,"payout":{"mode":"t+2","scheme":"SEPA"}`, []string{CORTPayoutInvalid}},
+		"payout scheme":  {testCORTTerms + `,"payout":{"mode":"t+1"}`, []string{CORTPayoutInvalid}},
+		"malformed":      {`"splits":{}`, []string{CORTMalformed}},
+	}
+	for name, tc := range cases {
+		var got []string
+		for _, v := range ValidateCORT([]byte(`{"type":"CORT",` + tc.terms + `}`)) {
+			got = append(got, v.Code)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: expected %v got %v", name, tc.want, got)
+		}
+	}
+}
+
+func TestVerifyCORTReportsViolations(t *testing.T) {
+	fsys := fstest.MapFS{"cort.json": {Data: []byte(`{"type":"CORT","parties":[{"id":"did:org:m","role":"merchant_of_record"},{"id":"did:org:b","role":"allocation_agent"}],"references":{},"splits":[{"party":"did:org:m","pct":1.2}]}`)}}
+	verifier, err := NewStaticVerifier(fsys, ".", FileMap{"urn:t:cort": "cort.json"})
+	if err != nil {
+		t.Fatalf("NewStaticVerifier: %v", err)
+	}
+	err = verifier.VerifyCORT(context.Background(), "urn:t:cort")
+	var cortErr *CORTError
+	if !errors.As(err, &cortErr) || len(cortErr.Violations) != 3 || cortErr.Violations[0].Code != CORTMissingRole {
+		t.Fatalf("expected missing role and split violations, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "splits sum to 1.200000") {
+		t.Fatalf("unexpected message %q", err)
+	}
+}
+
+func TestValidateCORTFixture(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "registry", "static", "tokens", "cort-vodafone-visa-2025.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	if violations := ValidateCORT(data); len(violations) != 0 {
+		t.Fatalf("expected the bundled CORT to be valid, got %v", violations)
+	}
+}
@@ -0,0 +1,75 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrDigestMismatch reports a token whose content does not match a declared hash.
+var ErrDigestMismatch = errors.New("token digest mismatch")
+
+// CanonicalDigest returns "sha256:<hex>" over the token's canonical JSON form:
+// object keys sorted, insignificant whitespace removed, numbers kept as
+// written and the self-referential top-level `hash` member omitted.
+func CanonicalDigest(payload []byte) (string, error) {
+	canonical, err := Canonicalize(payload)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// Canonicalize renders the canonical JSON form hashed by CanonicalDigest.
+func Canonicalize(payload []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	if obj, ok := doc.(map[string]any); ok {
+		delete(obj, "hash")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encode payload: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// VerifyDigest computes the canonical digest of payload and checks it against
+// the declared catalog hash and the payload's own `hash` member, each when set.
+func VerifyDigest(payload []byte, declared string) (string, error) {
+	digest, err := CanonicalDigest(payload)
+	if err != nil {
+		return "", err
+	}
+	if declared != "" && declared != digest {
+		return digest, fmt.Errorf("%w: catalog declares %s, content is %s", ErrDigestMismatch, declared, digest)
+	}
+	var envelope struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err == nil && envelope.Hash != "" && envelope.Hash != digest {
+		return digest, fmt.Errorf("%w: payload declares %s, content is %s", ErrDigestMismatch, envelope.Hash, digest)
+	}
+	return digest, nil
+}
+
+// Filter returns the entries for which keep reports true.
+func (c Catalog) Filter(keep func(CatalogEntry) bool) Catalog {
+	out := make(Catalog, 0, len(c))
+	for _, entry := range c {
+		if keep(entry) {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package verify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalDigestIgnoresLayout(t *testing.T) {
+	a, err := CanonicalDigest([]byte(`{"b":1.50,"a":{"y":"<x>","x":[1,2]},"hash":"sha256:self"}`))
+	if err != nil {
+		t.Fatalf("CanonicalDigest: %v", err)
+	}
+	b, err := CanonicalDigest([]byte("{\n  \"a\": {\"x\": [1, 2], \"y\": \"<x>\"},\n  \"b\": 1.50\n}"))
+	if err != nil {
+		t.Fatalf("CanonicalDigest: %v", err)
+	}
+	if a != b {
+		t.Fatalf("expected layout-independent digest, got %s and %s", a, b)
+	}
+	canonical, _ := Canonicalize([]byte(`{"b":1.50,"a":{"y":"<x>"}}`))
+	if string(canonical) != `{"a":{"y":"<x>"},"b":1.50}` {
+		t.Fatalf("unexpected canonical form %s", canonical)
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	payload := []byte(`{"type":"PSRT","version":"1"}`)
+	digest, err := VerifyDigest(payload, "")
+	if err != nil {
+		t.Fatalf("VerifyDigest: %v", err)
+	}
+	if _, err := VerifyDigest(payload, digest); err != nil {
+		t.Fatalf("expected declared digest to match: %v", err)
+	}
+	if _, err := VerifyDigest(payload, "sha256:other"); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected catalog mismatch, got %v", err)
+	}
+	tampered := []byte(`{"type":"PSRT","version":"2","hash":"` + digest + `"}`)
+	if _, err := VerifyDigest(tampered, ""); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected payload hash mismatch, got %v", err)
+	}
+}
+
+func TestDefaultCatalogDigests(t *testing.T) {
+	dir := filepath.Join("..", "registry", "static", "tokens")
+	for _, entry := range DefaultCatalog {
+		data, err := os.ReadFile(filepath.Join(dir, entry.File))
+		if err != nil {
+			t.Fatalf("read %s: %v", entry.File, err)
+		}
+		if _, err := VerifyDigest(data, entry.Hash); err != nil {
+			t.Fatalf("%s: %v", entry.URI, err)
+		}
+	}
+}
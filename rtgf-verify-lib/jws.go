@@ -0,0 +1,179 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JWS verification errors. ErrJWSMalformed covers anything that does not
+// parse as a compact or JSON-serialised JWS; the others wrap a well-formed
+// JWS whose signature cannot be trusted.
+var (
+	ErrJWSMalformed      = errors.New("malformed JWS")
+	ErrJWSUnsupportedAlg = errors.New("unsupported JWS algorithm")
+	ErrJWSUnknownKey     = errors.New("JWS signed by an untrusted key")
+	ErrJWSSignature      = errors.New("JWS signature invalid")
+)
+
+// KeySet maps JWKS key IDs to trusted Ed25519 public keys.
+type KeySet map[string]ed25519.PublicKey
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	Kid string `json:"kid"`
+	X   string `json:"x"`
+}
+
+// ParseJWKS reads the Ed25519 (OKP) keys from a JWKS document. Keys of other
+// types are ignored; a malformed Ed25519 key or a set without any is an error.
+func ParseJWKS(data []byte) (KeySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	keys := make(KeySet, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "OKP" || k.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid JWKS: key %q is not an Ed25519 public key", k.Kid)
+		}
+		keys[k.Kid] = ed25519.PublicKey(x)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("invalid JWKS: no Ed25519 keys")
+	}
+	return keys, nil
+}
+
+// Merge returns a KeySet holding the keys of ks and other. Keys already in ks
+// win on a kid collision.
+func (ks KeySet) Merge(other KeySet) KeySet {
+	out := make(KeySet, len(ks)+len(other))
+	for kid, key := range other {
+		out[kid] = key
+	}
+	for kid, key := range ks {
+		out[kid] = key
+	}
+	return out
+}
+
+type jwsHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Crit []string `json:"crit"`
+}
+
+type jwsSignature struct {
+	Protected string    `json:"protected"`
+	Header    jwsHeader `json:"header"`
+	Signature string    `json:"signature"`
+}
+
+// VerifyJWS checks a compact (`header.payload.signature`) or JSON-serialised
+// (flattened or general) JWS against keys and returns its decoded payload,
+// which must be a JSON object. Only EdDSA over Ed25519 is accepted; a general
+// JWS verifies when any one of its signatures does.
+func VerifyJWS(jws []byte, keys KeySet) (json.RawMessage, error) {
+	jws = bytes.TrimSpace(jws)
+	var (
+		payload string
+		sigs    []jwsSignature
+	)
+	if len(jws) > 0 && jws[0] == '{' {
+		var doc struct {
+			Payload *string `json:"payload"`
+			jwsSignature
+			Signatures []jwsSignature `json:"signatures"`
+		}
+		if err := json.Unmarshal(jws, &doc); err != nil || doc.Payload == nil {
+			return nil, ErrJWSMalformed
+		}
+		payload = *doc.Payload
+		sigs = doc.Signatures
+		if doc.Signature != "" {
+			sigs = append(sigs, doc.jwsSignature)
+		}
+	} else {
+		parts := strings.Split(string(jws), ".")
+		if len(parts) != 3 {
+			return nil, ErrJWSMalformed
+		}
+		payload = parts[1]
+		sigs = []jwsSignature{{Protected: parts[0], Signature: parts[2]}}
+	}
+	if len(sigs) == 0 {
+		return nil, ErrJWSMalformed
+	}
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrJWSMalformed
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, ErrJWSMalformed
+	}
+
+	err = ErrJWSMalformed
+	for _, sig := range sigs {
+		if err = verifySignature(sig, payload, keys); err == nil {
+			return json.RawMessage(body), nil
+		}
+	}
+	return nil, err
+}
+
+func verifySignature(sig jwsSignature, payload string, keys KeySet) error {
+	raw, err := base64.RawURLEncoding.DecodeString(sig.Protected)
+	if err != nil {
+		return ErrJWSMalformed
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return ErrJWSMalformed
+	}
+	if header.Kid == "" {
+		header.Kid = sig.Header.Kid
+	}
+	if header.Alg == "" {
+		header.Alg = sig.Header.Alg
+	}
+	if header.Alg != "EdDSA" {
+		return fmt.Errorf("%w %q", ErrJWSUnsupportedAlg, header.Alg)
+	}
+	if len(header.Crit) > 0 {
+		return fmt.Errorf("%w: critical header %q not understood", ErrJWSMalformed, header.Crit[0])
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return ErrJWSMalformed
+	}
+	input := []byte(sig.Protected + "." + payload)
+	if header.Kid != "" {
+		key, ok := keys[header.Kid]
+		if !ok {
+			return fmt.Errorf("%w %q", ErrJWSUnknownKey, header.Kid)
+		}
+		if !ed25519.Verify(key, input, signature) {
+			return ErrJWSSignature
+		}
+		return nil
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, input, signature) {
+			return nil
+		}
+	}
+	return ErrJWSSignature
+}
@@ -0,0 +1,89 @@
+package verify
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+var testSeed = []byte("rtgf-verify-lib-test-seed-000001")
+
+func testSign(t *testing.T, priv ed25519.PrivateKey, header, payload string) (string, string, string) {
+	t.Helper()
+	protected := base64.RawURLEncoding.EncodeToString([]byte(header))
+	body := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	sig := ed25519.Sign(priv, []byte(protected+"."+body))
+	return protected, body, base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestParseJWKS(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed(testSeed)
+	x := base64.RawURLEncoding.EncodeToString(priv.Public().(ed25519.PublicKey))
+	keys, err := ParseJWKS([]byte(`{"keys":[{"kty":"EC","crv":"P-256","kid":"ec"},{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"` + x + `"}]}`))
+	if err != nil {
+		t.Fatalf("ParseJWKS: %v", err)
+	}
+	if len(keys) != 1 || !keys["k1"].Equal(priv.Public()) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	for _, doc := range []string{`{`, `{"keys":[]}`, `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"AAAA"}]}`} {
+		if _, err := ParseJWKS([]byte(doc)); err == nil {
+			t.Fatalf("expected error for %s", doc)
+		}
+	}
+}
+
+func TestVerifyJWS(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed(testSeed)
+	keys := KeySet{"k1": priv.Public().(ed25519.PublicKey)}
+	payload := `{"type":"PSRT","revoked":false}`
+	protected, body, sig := testSign(t, priv, `{"alg":"EdDSA","kid":"k1"}`, payload)
+	bare, _, bareSig := testSign(t, priv, `{"alg":"EdDSA"}`, payload)
+
+	flattened, _ := json.Marshal(map[string]string{"protected": protected, "payload": body, "signature": sig})
+	general, _ := json.Marshal(map[string]any{
+		"payload": body,
+		"signatures": []map[string]string{
+			{"protected": protected, "signature": base64.RawURLEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))},
+			{"protected": bare, "signature": bareSig},
+		},
+	})
+	for name, jws := range map[string]string{
+		"compact":     protected + "." + body + "." + sig,
+		"compact kid": bare + "." + body + "." + bareSig,
+		"flattened":   string(flattened),
+		"general":     string(general),
+	} {
+		got, err := VerifyJWS([]byte(jws), keys)
+		if err != nil {
+			t.Fatalf("%s: VerifyJWS: %v", name, err)
+		}
+		if string(got) != payload {
+			t.Fatalf("%s: unexpected payload %s", name, got)
+		}
+	}
+
+	other := ed25519.NewKeyFromSeed([]byte("rtgf-verify-lib-test-seed-000002"))
+	forged, _, forgedSig := testSign(t, other, `{"alg":"EdDSA","kid":"k1"}`, payload)
+	unknown, _, unknownSig := testSign(t, priv, `{"alg":"EdDSA","kid":"k2"}`, payload)
+	hs, _, hsSig := testSign(t, priv, `{"alg":"HS256","kid":"k1"}`, payload)
+	notObject, notObjectBody, notObjectSig := testSign(t, priv, `{"alg":"EdDSA","kid":"k1"}`, `[1]`)
+	cases := map[string]struct {
+		jws  string
+		want error
+	}{
+		"forged":      {forged + "." + body + "." + forgedSig, ErrJWSSignature},
+		"unknown kid": {unknown + "." + body + "." + unknownSig, ErrJWSUnknownKey},
+		"alg":         {hs + "." + body + "." + hsSig, ErrJWSUnsupportedAlg},
+		"array":       {notObject + "." + notObjectBody + "." + notObjectSig, ErrJWSMalformed},
+		"parts":       {protected + "." + body, ErrJWSMalformed},
+		"plain json":  {payload, ErrJWSMalformed},
+	}
+	for name, tc := range cases {
+		if _, err := VerifyJWS([]byte(tc.jws), keys); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v got %v", name, tc.want, err)
+		}
+	}
+}
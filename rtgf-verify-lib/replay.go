@@ -0,0 +1,272 @@
+package verify
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MinReplayRetention is the shortest time an observed jti is remembered
+// (RTGF-REQ-021); tokens with a longer ttl_sec are kept for that long.
+const MinReplayRetention = 24 * time.Hour
+
+// DefaultReplayCapacity bounds the number of jti values a cache holds.
+const DefaultReplayCapacity = 100000
+
+// Replay errors returned by CheckReplay.
+var (
+	ErrReplayConflict = errors.New("jti seen with a different token hash")
+	ErrReplayRevoked  = errors.New("jti seen after its revocation")
+)
+
+// ReplayEntry is one observation of a token jti from an issuer.
+type ReplayEntry struct {
+	Issuer  string    `json:"iss"`
+	JTI     string    `json:"jti"`
+	Hash    string    `json:"hash"`
+	Revoked bool      `json:"revoked,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// ReplayCache remembers observed jti values per issuer. Observe records entry
+// and returns the unexpired entry previously held for the same issuer and
+// jti. Implementations must be safe for concurrent use.
+type ReplayCache interface {
+	Observe(entry ReplayEntry, now time.Time) (prior ReplayEntry, seen bool, err error)
+}
+
+// ReplayRetention returns how long a token with the given ttl_sec is kept.
+func ReplayRetention(ttl time.Duration) time.Duration {
+	return max(ttl, MinReplayRetention)
+}
+
+// CheckReplay observes entry in cache and reports ErrReplayRevoked when the
+// jti was seen revoked and is now presented unrevoked, or ErrReplayConflict
+// when an unrevoked presentation carries another hash. A revocation, once
+// observed, is kept until the entry expires.
+func CheckReplay(cache ReplayCache, entry ReplayEntry, now time.Time) error {
+	prior, seen, err := cache.Observe(entry, now)
+	if err != nil || !seen {
+		return err
+	}
+	switch {
+	case prior.Revoked && !entry.Revoked:
+		return fmt.Errorf("%w: %s/%s", ErrReplayRevoked, entry.Issuer, entry.JTI)
+	case entry.Revoked:
+		// Revoking a token changes its content, so the hash may differ.
+		return nil
+	case prior.Hash != "" && entry.Hash != "" && prior.Hash != entry.Hash:
+		return fmt.Errorf("%w: %s/%s", ErrReplayConflict, entry.Issuer, entry.JTI)
+	}
+	return nil
+}
+
+type replayKey struct {
+	issuer, jti string
+}
+
+// MemoryReplayCache is an in-memory LRU of jti observations. Entries expire
+// at their retention deadline; the least recently observed entry is evicted
+// once capacity is reached.
+type MemoryReplayCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[replayKey]*list.Element
+}
+
+// NewMemoryReplayCache returns an empty cache holding at most capacity
+// entries (DefaultReplayCapacity when capacity <= 0).
+func NewMemoryReplayCache(capacity int) *MemoryReplayCache {
+	if capacity <= 0 {
+		capacity = DefaultReplayCapacity
+	}
+	return &MemoryReplayCache{capacity: capacity, order: list.New(), entries: make(map[replayKey]*list.Element)}
+}
+
+// Observe implements ReplayCache. The stored entry keeps the latest hash, the
+// expiry of the first observation and any earlier revocation, so presenting
+// a jti again never extends its retention.
+func (c *MemoryReplayCache) Observe(entry ReplayEntry, now time.Time) (ReplayEntry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, prior, seen := c.observe(entry, now)
+	return prior, seen, nil
+}
+
+// Len returns the number of unexpired entries.
+func (c *MemoryReplayCache) Len(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		if e.Value.(ReplayEntry).Expires.After(now) {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *MemoryReplayCache) observe(entry ReplayEntry, now time.Time) (stored, prior ReplayEntry, seen bool) {
+	key := replayKey{entry.Issuer, entry.JTI}
+	stored = entry
+	if el, ok := c.entries[key]; ok {
+		if prior = el.Value.(ReplayEntry); prior.Expires.After(now) {
+			seen = true
+			stored.Revoked = stored.Revoked || prior.Revoked
+			stored.Expires = prior.Expires
+		}
+		el.Value = stored
+		c.order.MoveToFront(el)
+	} else {
+		c.entries[key] = c.order.PushFront(stored)
+	}
+	c.evict(now)
+	if !seen {
+		prior = ReplayEntry{}
+	}
+	return stored, prior, seen
+}
+
+func (c *MemoryReplayCache) evict(now time.Time) {
+	for e := c.order.Back(); e != nil; e = c.order.Back() {
+		entry := e.Value.(ReplayEntry)
+		if c.order.Len() <= c.capacity && entry.Expires.After(now) {
+			return
+		}
+		c.order.Remove(e)
+		delete(c.entries, replayKey{entry.Issuer, entry.JTI})
+	}
+}
+
+// FileReplayCache is a MemoryReplayCache journaled to a JSON-lines file so
+// observations survive restarts. Only observations that change the stored
+// entry are appended, and the journal is rewritten from the cache once it
+// holds journalCompactFactor times the cache capacity in lines, so it stays
+// bounded like the cache.
+type FileReplayCache struct {
+	mem   *MemoryReplayCache
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	lines int
+}
+
+// journalCompactFactor bounds the journal to this many lines per cache entry.
+const journalCompactFactor = 2
+
+// OpenFileReplayCache loads the journal at path (creating it if missing),
+// drops expired entries, compacts the file and appends new observations.
+func OpenFileReplayCache(path string, capacity int, now time.Time) (*FileReplayCache, error) {
+	mem := NewMemoryReplayCache(capacity)
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			var entry ReplayEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("replay journal %s line %d: %w", path, line, err)
+			}
+			mem.observe(entry, now)
+		}
+		err := scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read replay journal %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("open replay journal: %w", err)
+	}
+	c := &FileReplayCache{mem: mem, path: path}
+	if err := c.compact(now); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// compact rewrites the journal with the unexpired cache entries, oldest
+// first, and reopens it for appending. Callers hold c.mu or own c.
+func (c *FileReplayCache) compact(now time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("compact replay journal: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	lines := 0
+	for e := c.mem.order.Back(); e != nil && err == nil; e = e.Prev() {
+		if entry := e.Value.(ReplayEntry); entry.Expires.After(now) {
+			err = enc.Encode(entry)
+			lines++
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("compact replay journal: %w", err)
+	}
+	file, err := os.OpenFile(c.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open replay journal: %w", err)
+	}
+	if c.file != nil {
+		c.file.Close()
+	}
+	c.file, c.lines = file, lines
+	return nil
+}
+
+// Observe implements ReplayCache, appending the stored entry to the journal
+// when it differs from the entry previously held: on first sight, on
+// revocation or on a new hash.
+func (c *FileReplayCache) Observe(entry ReplayEntry, now time.Time) (ReplayEntry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, prior, seen := c.mem.observe(entry, now)
+	if seen && sameReplayEntry(stored, prior) {
+		return prior, seen, nil
+	}
+	if c.lines >= journalCompactFactor*c.mem.capacity {
+		if err := c.compact(now); err != nil {
+			return prior, seen, err
+		}
+		return prior, seen, nil
+	}
+	line, err := json.Marshal(stored)
+	if err != nil {
+		return prior, seen, err
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return prior, seen, fmt.Errorf("append replay journal: %w", err)
+	}
+	c.lines++
+	return prior, seen, nil
+}
+
+func sameReplayEntry(a, b ReplayEntry) bool {
+	return a.Issuer == b.Issuer && a.JTI == b.JTI && a.Hash == b.Hash &&
+		a.Revoked == b.Revoked && a.Expires.Equal(b.Expires)
+}
+
+// Close closes the journal.
+func (c *FileReplayCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Close()
+}
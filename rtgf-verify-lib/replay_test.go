@@ -0,0 +1,195 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckReplay(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	expires := now.Add(ReplayRetention(time.Hour))
+	if expires != now.Add(MinReplayRetention) {
+		t.Fatalf("expected the 24h floor, got %s", expires)
+	}
+	cache := NewMemoryReplayCache(0)
+	entry := ReplayEntry{Issuer: "did:web:registry", JTI: "jti-1", Hash: "sha256:a", Expires: expires}
+
+	if err := CheckReplay(cache, entry, now); err != nil {
+		t.Fatalf("first observation: %v", err)
+	}
+	if err := CheckReplay(cache, entry, now); err != nil {
+		t.Fatalf("repeat observation: %v", err)
+	}
+	other := entry
+	other.Issuer = "did:web:partner"
+	other.Hash = "sha256:b"
+	if err := CheckReplay(cache, other, now); err != nil {
+		t.Fatalf("same jti from another issuer: %v", err)
+	}
+	conflict := entry
+	conflict.Hash = "sha256:b"
+	if err := CheckReplay(cache, conflict, now); !errors.Is(err, ErrReplayConflict) {
+		t.Fatalf("expected ErrReplayConflict, got %v", err)
+	}
+
+	revoked := entry
+	revoked.JTI, revoked.Revoked = "jti-2", true
+	if err := CheckReplay(cache, revoked, now); err != nil {
+		t.Fatalf("revoked observation: %v", err)
+	}
+	replayed := revoked
+	replayed.Revoked = false
+	if err := CheckReplay(cache, replayed, now); !errors.Is(err, ErrReplayRevoked) {
+		t.Fatalf("expected ErrReplayRevoked, got %v", err)
+	}
+	if err := CheckReplay(cache, replayed, expires); err != nil {
+		t.Fatalf("expected the expired observation to be forgotten, got %v", err)
+	}
+}
+
+func TestMemoryReplayCacheEvictsLeastRecent(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewMemoryReplayCache(2)
+	for _, jti := range []string{"a", "b", "a", "c"} {
+		cache.Observe(ReplayEntry{JTI: jti, Expires: now.Add(time.Hour)}, now)
+	}
+	if _, seen, _ := cache.Observe(ReplayEntry{JTI: "a", Expires: now.Add(time.Hour)}, now); !seen {
+		t.Fatalf("expected recently observed jti a to be kept")
+	}
+	if _, seen, _ := cache.Observe(ReplayEntry{JTI: "b", Expires: now.Add(time.Hour)}, now); seen {
+		t.Fatalf("expected least recently observed jti b to be evicted")
+	}
+	if n := cache.Len(now); n != 2 {
+		t.Fatalf("expected 2 entries, got %d", n)
+	}
+}
+
+func TestFileReplayCache(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "replay.jsonl")
+	cache, err := OpenFileReplayCache(path, 0, now)
+	if err != nil {
+		t.Fatalf("OpenFileReplayCache: %v", err)
+	}
+	revoked := ReplayEntry{Issuer: "iss", JTI: "kept", Hash: "sha256:a", Revoked: true, Expires: now.Add(48 * time.Hour)}
+	stale := ReplayEntry{Issuer: "iss", JTI: "stale", Hash: "sha256:b", Expires: now.Add(time.Hour)}
+	for _, entry := range []ReplayEntry{revoked, stale} {
+		if err := CheckReplay(cache, entry, now); err != nil {
+			t.Fatalf("observe %s: %v", entry.JTI, err)
+		}
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	later := now.Add(2 * time.Hour)
+	reopened, err := OpenFileReplayCache(path, 0, later)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	replayed := revoked
+	replayed.Revoked = false
+	if err := CheckReplay(reopened, replayed, later); !errors.Is(err, ErrReplayRevoked) {
+		t.Fatalf("expected the revocation to survive a restart, got %v", err)
+	}
+	if _, seen, _ := reopened.mem.Observe(stale, later); seen {
+		t.Fatalf("expected the expired entry to be dropped")
+	}
+	data, _ := os.ReadFile(path)
+	if len(data) == 0 {
+		t.Fatalf("expected a compacted journal")
+	}
+
+	os.WriteFile(path, []byte("not json\n"), 0o600)
+	if _, err := OpenFileReplayCache(path, 0, now); err == nil {
+		t.Fatalf("expected a corrupt journal to be rejected")
+	}
+}
+
+func TestCheckReplayAcceptsRevocationUpdate(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewMemoryReplayCache(0)
+	entry := ReplayEntry{JTI: "jti", Hash: "sha256:a", Expires: now.Add(time.Hour)}
+	CheckReplay(cache, entry, now)
+	revoked := entry
+	revoked.Hash, revoked.Revoked = "sha256:b", true
+	if err := CheckReplay(cache, revoked, now); err != nil {
+		t.Fatalf("expected the revoked reissue to be accepted, got %v", err)
+	}
+	if err := CheckReplay(cache, entry, now); !errors.Is(err, ErrReplayRevoked) {
+		t.Fatalf("expected ErrReplayRevoked, got %v", err)
+	}
+}
+
+func TestFileReplayCacheJournalStaysBounded(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "replay.jsonl")
+	cache, err := OpenFileReplayCache(path, 2, now)
+	if err != nil {
+		t.Fatalf("OpenFileReplayCache: %v", err)
+	}
+	defer cache.Close()
+	lines := func() int {
+		data, _ := os.ReadFile(path)
+		return strings.Count(string(data), "\n")
+	}
+	entry := ReplayEntry{Issuer: "iss", JTI: "jti-0", Hash: "sha256:a", Expires: now.Add(48 * time.Hour)}
+	for i := 0; i < 10; i++ {
+		if err := CheckReplay(cache, entry, now); err != nil {
+			t.Fatalf("repeat %d: %v", i, err)
+		}
+	}
+	if got := lines(); got != 1 {
+		t.Fatalf("expected repeats of an unchanged jti to append once, got %d lines", got)
+	}
+	revoked := entry
+	revoked.Revoked = true
+	CheckReplay(cache, revoked, now)
+	if got := lines(); got != 2 {
+		t.Fatalf("expected the revocation to be appended, got %d lines", got)
+	}
+	for i := 1; i <= 20; i++ {
+		next := entry
+		next.JTI = fmt.Sprintf("jti-%d", i)
+		if err := CheckReplay(cache, next, now); err != nil {
+			t.Fatalf("observe %s: %v", next.JTI, err)
+		}
+		if got := lines(); got > journalCompactFactor*2 {
+			t.Fatalf("expected at most %d journal lines, got %d", journalCompactFactor*2, got)
+		}
+	}
+}
+
+func TestReplayExpiryAnchoredToFirstObservation(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "replay.jsonl")
+	cache, err := OpenFileReplayCache(path, 0, now)
+	if err != nil {
+		t.Fatalf("OpenFileReplayCache: %v", err)
+	}
+	defer cache.Close()
+	first := ReplayEntry{Issuer: "iss", JTI: "jti", Hash: "sha256:a", Expires: now.Add(MinReplayRetention)}
+	cache.Observe(first, now)
+	for i := 1; i <= 5; i++ {
+		at := now.Add(time.Duration(i) * time.Hour)
+		repeat := first
+		repeat.Expires = at.Add(MinReplayRetention)
+		prior, seen, err := cache.Observe(repeat, at)
+		if err != nil || !seen || !prior.Expires.Equal(first.Expires) {
+			t.Fatalf("repeat %d: expected expiry %s to hold, got %+v (seen %v, err %v)", i, first.Expires, prior, seen, err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if got := strings.Count(string(data), "\n"); got != 1 {
+		t.Fatalf("expected repeat observations not to grow the journal, got %d lines", got)
+	}
+	if _, seen, _ := cache.Observe(first, first.Expires); seen {
+		t.Fatalf("expected the entry to expire a retention period after its first observation")
+	}
+}
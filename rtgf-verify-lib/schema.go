@@ -0,0 +1,437 @@
+package verify
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// The embedded schemas are copies of the repository's schemas/ directory,
+// which lies outside this module; TestEmbeddedSchemasMatchRepository keeps
+// them in sync.
+//
+//go:generate sh -c "rm -rf schemas && cp -r ../schemas schemas"
+
+//go:embed schemas
+var schemaFiles embed.FS
+
+// tokenSchemas maps token types to their embedded schema.
+var tokenSchemas = map[string]string{
+	"RMT":  "schemas/imt-rmt/rmt.schema.json",
+	"IMT":  "schemas/imt-rmt/imt.schema.json",
+	"RRMT": "schemas/payments/rrmt.schema.json",
+	"CORT": "schemas/payments/cort.schema.json",
+	"PSRT": "schemas/payments/psrt.schema.json",
+	"AMLS": "schemas/payments/amls.schema.json",
+	"AMLV": "schemas/payments/amlv.schema.json",
+}
+
+// mandalaProofSchema describes the Mandala proof receipts tokens and requests
+// carry as evidence.
+const mandalaProofSchema = "schemas/mandala/mandala-proof.schema.json"
+
+// SchemaViolation is one failed JSON Schema keyword. Pointer is the RFC 6901
+// JSON Pointer of the offending value ("" for the document root).
+type SchemaViolation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+func (v SchemaViolation) String() string {
+	if v.Pointer == "" {
+		return "(root): " + v.Message
+	}
+	return v.Pointer + ": " + v.Message
+}
+
+// SchemaError lists every schema violation of a token payload.
+type SchemaError struct {
+	URI        string
+	Type       string
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.String())
+	}
+	return fmt.Sprintf("token %s violates the %s schema: %s", e.URI, e.Type, strings.Join(parts, "; "))
+}
+
+// ValidateSchema validates payload against the embedded schema for the token
+// type. It returns nil when the payload conforms or the type has no schema.
+// The enforced keywords are $ref (by $id), type, const, enum, required,
+// properties, additionalProperties, items, min/maxItems, min/maxLength,
+// pattern, (exclusive) minimum/maximum and the date-time and uri formats;
+// embedded schemas using anything else fail to load.
+func ValidateSchema(tokenType string, payload []byte) []SchemaViolation {
+	file, ok := tokenSchemas[strings.ToUpper(tokenType)]
+	if !ok {
+		return nil
+	}
+	return validateFile(file, payload)
+}
+
+// ValidateMandalaProof validates a Project Mandala proof receipt against the
+// embedded mandala-proof schema.
+func ValidateMandalaProof(payload []byte) []SchemaViolation {
+	return validateFile(mandalaProofSchema, payload)
+}
+
+func validateFile(file string, payload []byte) []SchemaViolation {
+	set, err := loadSchemas()
+	if err != nil {
+		return []SchemaViolation{{"", err.Error()}}
+	}
+	schema, ok := set.byFile[file]
+	if !ok {
+		return []SchemaViolation{{"", "schema " + file + " is not embedded"}}
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return []SchemaViolation{{"", fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	var violations []SchemaViolation
+	set.validate(schema, doc, "", &violations)
+	return violations
+}
+
+type schemaSet struct {
+	byFile map[string]map[string]any
+	byID   map[string]map[string]any
+}
+
+var (
+	schemasOnce sync.Once
+	schemas     *schemaSet
+	schemasErr  error
+)
+
+func loadSchemas() (*schemaSet, error) {
+	schemasOnce.Do(func() {
+		schemas, schemasErr = readSchemas(schemaFiles)
+	})
+	return schemas, schemasErr
+}
+
+// readSchemas parses every schema under schemas/ in fsys, rejecting any that
+// uses a keyword validate does not enforce.
+func readSchemas(fsys fs.FS) (*schemaSet, error) {
+	set := &schemaSet{byFile: make(map[string]map[string]any), byID: make(map[string]map[string]any)}
+	err := fs.WalkDir(fsys, "schemas", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var schema map[string]any
+		if err := dec.Decode(&schema); err != nil {
+			return fmt.Errorf("load schema %s: %w", path, err)
+		}
+		if err := checkKeywords(schema, ""); err != nil {
+			return fmt.Errorf("load schema %s: %w", path, err)
+		}
+		set.byFile[path] = schema
+		if id, ok := schema["$id"].(string); ok {
+			set.byID[id] = schema
+		}
+		return nil
+	})
+	return set, err
+}
+
+// schemaKeywords are the keywords validate enforces, plus the annotations it
+// may safely ignore.
+var schemaKeywords = map[string]bool{
+	"$schema": true, "$id": true, "title": true, "description": true,
+	"$ref": true, "type": true, "const": true, "enum": true,
+	"required": true, "properties": true, "additionalProperties": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "format": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+}
+
+// checkKeywords fails on the first keyword, subschema form or format below ptr
+// that validate would otherwise silently skip, so a schema can never promise
+// more than is enforced.
+func checkKeywords(schema map[string]any, ptr string) error {
+	for _, key := range sortedKeys(schema) {
+		at := ptr + "/" + escapePointer(key)
+		if !schemaKeywords[key] {
+			return fmt.Errorf("%s: unsupported keyword %q", at, key)
+		}
+		switch key {
+		case "properties":
+			properties, ok := schema[key].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: must be an object", at)
+			}
+			for _, name := range sortedKeys(properties) {
+				sub, ok := properties[name].(map[string]any)
+				if !ok {
+					return fmt.Errorf("%s/%s: must be a schema object", at, escapePointer(name))
+				}
+				if err := checkKeywords(sub, at+"/"+escapePointer(name)); err != nil {
+					return err
+				}
+			}
+		case "items":
+			sub, ok := schema[key].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: only a single items schema is supported", at)
+			}
+			if err := checkKeywords(sub, at); err != nil {
+				return err
+			}
+		case "additionalProperties":
+			switch extra := schema[key].(type) {
+			case bool:
+			case map[string]any:
+				if err := checkKeywords(extra, at); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%s: must be a boolean or schema object", at)
+			}
+		case "format":
+			if format, _ := schema[key].(string); format != "date-time" && format != "uri" {
+				return fmt.Errorf("%s: unsupported format %q", at, schema[key])
+			}
+		case "pattern":
+			pattern, _ := schema[key].(string)
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("%s: %w", at, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *schemaSet) validate(schema map[string]any, value any, ptr string, out *[]SchemaViolation) {
+	fail := func(format string, args ...any) {
+		*out = append(*out, SchemaViolation{ptr, fmt.Sprintf(format, args...)})
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		target, ok := s.byID[ref]
+		if !ok {
+			fail("unresolvable $ref %q", ref)
+		} else {
+			s.validate(target, value, ptr, out)
+		}
+	}
+	if want, ok := schema["type"]; ok && !matchesType(want, value) {
+		fail("must be %s, got %s", describeType(want), jsonType(value))
+		return
+	}
+	if want, ok := schema["const"]; ok && !jsonEqual(want, value) {
+		fail("must equal %s", compactJSON(want))
+	}
+	if options, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, option := range options {
+			found = found || jsonEqual(option, value)
+		}
+		if !found {
+			fail("must be one of %s", compactJSON(options))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if key, ok := name.(string); ok {
+					if _, present := v[key]; !present {
+						*out = append(*out, SchemaViolation{ptr + "/" + escapePointer(key), "is required"})
+					}
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for _, key := range sortedKeys(v) {
+			child := ptr + "/" + escapePointer(key)
+			if sub, ok := properties[key].(map[string]any); ok {
+				s.validate(sub, v[key], child, out)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					*out = append(*out, SchemaViolation{child, "is not allowed"})
+				}
+			case map[string]any:
+				s.validate(extra, v[key], child, out)
+			}
+		}
+	case []any:
+		if n, ok := schemaInt(schema["minItems"]); ok && len(v) < n {
+			fail("must have at least %d items", n)
+		}
+		if n, ok := schemaInt(schema["maxItems"]); ok && len(v) > n {
+			fail("must have at most %d items", n)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				s.validate(items, item, ptr+"/"+strconv.Itoa(i), out)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n, ok := schemaInt(schema["minLength"]); ok && length < n {
+			fail("must be at least %d characters", n)
+		}
+		if n, ok := schemaInt(schema["maxLength"]); ok && length > n {
+			fail("must be at most %d characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				fail("must match %q", pattern)
+			}
+		}
+		if format, ok := schema["format"].(string); ok && !matchesFormat(format, v) {
+			fail("must be a valid %s", format)
+		}
+	case json.Number:
+		n, _ := new(big.Rat).SetString(v.String())
+		bound := func(keyword string, violated func(cmp int) bool, relation string) {
+			if limit, ok := schemaRat(schema[keyword]); ok && n != nil && violated(n.Cmp(limit)) {
+				fail("must be %s %s", relation, limit.RatString())
+			}
+		}
+		bound("minimum", func(c int) bool { return c < 0 }, ">=")
+		bound("maximum", func(c int) bool { return c > 0 }, "<=")
+		bound("exclusiveMinimum", func(c int) bool { return c <= 0 }, ">")
+		bound("exclusiveMaximum", func(c int) bool { return c >= 0 }, "<")
+	}
+}
+
+func matchesType(want, value any) bool {
+	switch w := want.(type) {
+	case string:
+		return matchesSingleType(w, value)
+	case []any:
+		for _, option := range w {
+			if name, ok := option.(string); ok && matchesSingleType(name, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesSingleType(want string, value any) bool {
+	got := jsonType(value)
+	if want == "number" && got == "integer" {
+		return true
+	}
+	return want == got
+}
+
+func describeType(want any) string {
+	if list, ok := want.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(want)
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if n, ok := new(big.Rat).SetString(v.String()); ok && n.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func matchesFormat(format, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	}
+	return true
+}
+
+func jsonEqual(a, b any) bool {
+	if x, ok := a.(json.Number); ok {
+		if y, ok := b.(json.Number); ok {
+			rx, okx := new(big.Rat).SetString(x.String())
+			ry, oky := new(big.Rat).SetString(y.String())
+			return okx && oky && rx.Cmp(ry) == 0
+		}
+	}
+	return compactJSON(a) == compactJSON(b)
+}
+
+func compactJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func schemaInt(v any) (int, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	return int(i), err == nil
+}
+
+func schemaRat(v any) (*big.Rat, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(n.String())
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapePointer escapes a member name for use in a JSON Pointer (RFC 6901).
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,168 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// Minimal schema-valid claims per token type, without the type discriminator.
+// testIMTClaims leaves out the corridor, which tests vary.
+const (
+	testRMTClaims = `"rmt_id":"urn:rmt","jurisdiction":"EU","domain":"payments_psd3",` +
+		`"effective_date":"2025-10-01T00:00:00Z","expires_at":"2026-10-01T00:00:00Z","policy_snapshot_hash":"sha256:test"`
+	testIMTClaims = `"imt_id":"urn:imt","domain":"payments_psd3",` +
+		`"effective_date":"2025-10-01T00:00:00Z","expires_at":"2026-10-01T00:00:00Z","policy_snapshot_hash":"sha256:test"`
+	testRRMTClaims = `"domain":"payments_psd3","jurisdiction":["EU"],"currency":"EUR","pricing":{"mode":"per_txn"}`
+	testPSRTClaims = `"acquirer":"did:org:acq","scheme":"VISA","capture":{"mode":"auto"}`
+	testAMLSClaims = `"subject":"did:org:vodafone","provider":"did:org:screener","result":"clear","screened_at":"2025-10-01T00:00:00Z"`
+	testAMLVClaims = `"subject":"did:org:vodafone","verifier":"did:org:verifier","screening":"urn:lane2:token:AMLS:ACME:42",` +
+		`"verified_at":"2025-10-01T00:00:00Z"`
+)
+
+func TestValidateSchema(t *testing.T) {
+	proof := `{"proof_type":"zkp_sanctions","provider":"https://mandala.example","ccid":"cc-1","proof_hash":"sha256:p","version":"1","verified":true}`
+	cases := map[string]struct {
+		typ, payload string
+		want         []SchemaViolation
+	}{
+		"rmt":      {"RMT", `{"type":"RMT",` + testRMTClaims + `}`, nil},
+		"imt":      {"imt", `{"type":"IMT",` + testIMTClaims + `,"corridor":"EU-SG","mandala_proofs":[` + proof + `]}`, nil},
+		"rrmt":     {"RRMT", `{"type":"RRMT",` + testRRMTClaims + `}`, nil},
+		"cort":     {"CORT", `{"type":"CORT",` + testCORTTerms + `}`, nil},
+		"psrt":     {"PSRT", `{"type":"PSRT",` + testPSRTClaims + `}`, nil},
+		"amls":     {"AMLS", `{"type":"AMLS",` + testAMLSClaims + `}`, nil},
+		"amlv":     {"AMLV", `{"type":"AMLV",` + testAMLVClaims + `}`, nil},
+		"noSchema": {"OTHER", `{"anything":1}`, nil},
+		"missing": {"RRMT", `{"type":"RRMT","domain":"payments_psd3","jurisdiction":["EU"],"pricing":{"mode":"per_txn"}}`,
+			[]SchemaViolation{{"/currency", "is required"}}},
+		"const": {"PSRT", `{"type":"RRMT",` + testPSRTClaims + `}`,
+			[]SchemaViolation{{"/type", `must equal "PSRT"`}}},
+		"enumAndType": {"RRMT", `{"type":"RRMT","domain":"payments_psd3","jurisdiction":"EU","currency":"EUR","pricing":{"mode":"flat"}}`,
+			[]SchemaViolation{{"/jurisdiction", "must be array, got string"}, {"/pricing/mode", `must be one of ["tiered","per_call","per_byte","per_txn"]`}}},
+		"minItems": {"RRMT", `{"type":"RRMT","domain":"payments_psd3","jurisdiction":[],"currency":"EUR","pricing":{"mode":"per_txn"}}`,
+			[]SchemaViolation{{"/jurisdiction", "must have at least 1 items"}}},
+		"integer": {"PSRT", `{"type":"PSRT","acquirer":"a","scheme":"VISA","capture":{"mode":"auto","window_sec":1.5}}`,
+			[]SchemaViolation{{"/capture/window_sec", "must be integer, got number"}}},
+		"minimum": {"PSRT", `{"type":"PSRT","acquirer":"a","scheme":"VISA","capture":{"mode":"auto","window_sec":-1}}`,
+			[]SchemaViolation{{"/capture/window_sec", "must be >= 0"}}},
+		"format": {"AMLS", `{"type":"AMLS","subject":"s","provider":"p","result":"clear","screened_at":"yesterday"}`,
+			[]SchemaViolation{{"/screened_at", "must be a valid date-time"}}},
+		"nestedItem": {"CORT", `{"type":"CORT","parties":[{"id":"a","role":"merchant_of_record"},{"id":"b"}],"references":{},"splits":[{"party":"a","pct":1}]}`,
+			[]SchemaViolation{{"/parties/1/role", "is required"}}},
+		"ref": {"IMT", `{` + testIMTClaims + `,"corridor":"EU-SG","mandala_proofs":[{"proof_type":"x","provider":"node","ccid":"c","proof_hash":"h","version":"1"}]}`,
+			[]SchemaViolation{{"/mandala_proofs/0/verified", "is required"}, {"/mandala_proofs/0/provider", "must be a valid uri"}}},
+		"notObject": {"RMT", `[]`, []SchemaViolation{{"", "must be object, got array"}}},
+	}
+	for name, tc := range cases {
+		if got := ValidateSchema(tc.typ, []byte(tc.payload)); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: expected %v got %v", name, tc.want, got)
+		}
+	}
+}
+
+func TestValidateMandalaProof(t *testing.T) {
+	valid := `{"proof_type":"zkp_sanctions","provider":"https://mandala.example","ccid":"cc-1","proof_hash":"sha256:p","version":"1","verified":false}`
+	if got := ValidateMandalaProof([]byte(valid)); got != nil {
+		t.Fatalf("expected valid receipt, got %v", got)
+	}
+	want := []SchemaViolation{{"/version", "is required"}, {"/verified", "must be boolean, got string"}}
+	got := ValidateMandalaProof([]byte(`{"proof_type":"zkp_sanctions","provider":"https://mandala.example","ccid":"cc-1","proof_hash":"sha256:p","verified":"yes"}`))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v got %v", want, got)
+	}
+}
+
+func TestEscapePointer(t *testing.T) {
+	if got := escapePointer("a/b~c"); got != "a~1b~0c" {
+		t.Fatalf("escapePointer = %q", got)
+	}
+}
+
+func TestNewStaticVerifierRejectsSchemaViolations(t *testing.T) {
+	fsys := fstest.MapFS{"psrt.json": {Data: []byte(`{"type":"PSRT","acquirer":"did:org:acq","capture":{"mode":"later"}}`)}}
+	_, err := NewStaticVerifier(fsys, ".", FileMap{"urn:lane2:token:PSRT:VISA:1": "psrt.json"})
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected schema error, got %v", err)
+	}
+	want := []SchemaViolation{{"/scheme", "is required"}, {"/capture/mode", `must be one of ["auto","manual"]`}}
+	if schemaErr.URI != "urn:lane2:token:PSRT:VISA:1" || schemaErr.Type != "PSRT" || !reflect.DeepEqual(schemaErr.Violations, want) {
+		t.Fatalf("unexpected schema error %+v", schemaErr)
+	}
+}
+
+func TestEmbeddedSchemasMatchRepository(t *testing.T) {
+	repo := filepath.Join("..", "schemas")
+	seen := 0
+	err := filepath.WalkDir(repo, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(repo, path)
+		want, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		got, err := schemaFiles.ReadFile("schemas/" + filepath.ToSlash(rel))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("embedded schemas/%s differs from the repository copy; run go generate", rel)
+		}
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk %s: %v", repo, err)
+	}
+	if seen == 0 {
+		t.Fatalf("no schemas found under %s", repo)
+	}
+}
+
+func TestEmbeddedSchemasUseSupportedKeywords(t *testing.T) {
+	set, err := readSchemas(schemaFiles)
+	if err != nil {
+		t.Fatalf("embedded schemas: %v", err)
+	}
+	for _, file := range tokenSchemas {
+		if set.byFile[file] == nil {
+			t.Errorf("token schema %s not loaded", file)
+		}
+	}
+	if set.byFile[mandalaProofSchema] == nil {
+		t.Errorf("mandala proof schema not loaded")
+	}
+}
+
+func TestReadSchemasRejectsUnsupportedKeywords(t *testing.T) {
+	cases := map[string]struct {
+		schema string
+		want   string
+	}{
+		"allOf":             {`{"allOf":[{"type":"object"}]}`, `/allOf: unsupported keyword "allOf"`},
+		"nested":            {`{"properties":{"a":{"items":{"oneOf":[]}}}}`, `/properties/a/items/oneOf: unsupported keyword "oneOf"`},
+		"patternProperties": {`{"additionalProperties":{"patternProperties":{}}}`, `/additionalProperties/patternProperties: unsupported keyword "patternProperties"`},
+		"tupleItems":        {`{"items":[{"type":"string"}]}`, `/items: only a single items schema is supported`},
+		"format":            {`{"properties":{"mail":{"format":"email"}}}`, `/properties/mail/format: unsupported format "email"`},
+	}
+	for name, tc := range cases {
+		fsys := fstest.MapFS{"schemas/bad.schema.json": {Data: []byte(tc.schema)}}
+		_, err := readSchemas(fsys)
+		if want := "load schema schemas/bad.schema.json: " + tc.want; err == nil || err.Error() != want {
+			t.Errorf("%s: got %v, want %s", name, err, want)
+		}
+	}
+}
+
+func TestBundledFixturesMatchSchemas(t *testing.T) {
+	fsys := os.DirFS(filepath.Join("..", "registry", "static", "tokens"))
+	if _, err := NewStaticVerifierFromCatalog(fsys, ".", DefaultCatalog, time.Time{}); err != nil {
+		t.Fatalf("bundled fixtures: %v", err)
+	}
+}
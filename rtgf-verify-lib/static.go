@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // StaticVerifier loads predefined token fixtures and exposes verification helpers
@@ -32,17 +33,13 @@ type TokenInfo struct {
 // FileMap links canonical token URIs to fixture filenames.
 type FileMap map[string]string
 
-var (
-	// DefaultFileMap enumerates the sandbox fixtures bundled with RTGF docs.
-DefaultFileMap = FileMap{
-	"urn:lane2:token:RMT:EU:PSD3:3.2":         "rrmt-eu-psd3-2025.json",
-	"urn:lane2:token:IMT:EU:SG:2025":          "imt-eu-sg-2025.json",
-	"urn:lane2:token:CORT:VODAFONE.VISA:2025": "cort-vodafone-visa-2025.json",
-	"urn:lane2:token:PSRT:VISA:ACQ-123":       "psrt-visa-acq-123.json",
-}
-)
+// DefaultFileMap enumerates the sandbox fixtures bundled with RTGF docs. The
+// default catalog holds one version per URI, so any instant selects them all.
+var DefaultFileMap = DefaultCatalog.FileMap(time.Time{})
 
 // NewStaticVerifier reads fixtures from the provided filesystem rooted at baseDir.
+// Each fixture must conform to the JSON Schema for its type; a violation is
+// returned as a *SchemaError.
 func NewStaticVerifier(fsys fs.FS, baseDir string, files FileMap) (*StaticVerifier, error) {
 	if files == nil {
 		files = DefaultFileMap
@@ -70,18 +67,114 @@ func NewStaticVerifier(fsys fs.FS, baseDir string, files FileMap) (*StaticVerifi
 			}
 			meta[uri] = info
 		}
+		typ := info.Type
+		if typ == "" {
+			typ = detectType(uri)
+		}
+		if violations := ValidateSchema(typ, data); len(violations) > 0 {
+			return nil, fmt.Errorf("fixture %s: %w", path, &SchemaError{URI: uri, Type: strings.ToUpper(typ), Violations: violations})
+		}
 	}
 	return &StaticVerifier{tokens: resolved, meta: meta}, nil
 }
 
+// NewStaticVerifierFromCatalog loads the fixtures listed in catalog, taking
+// for each URI the version current at now (see CurrentVersion). Catalog
+// metadata fills fields the payloads leave empty, notably the token hash.
+// The selection is fixed until the verifier is rebuilt.
+func NewStaticVerifierFromCatalog(fsys fs.FS, baseDir string, catalog Catalog, now time.Time) (*StaticVerifier, error) {
+	if len(catalog) == 0 {
+		return nil, errors.New("no token fixtures supplied")
+	}
+	current := catalog.Current(now)
+	v, err := NewStaticVerifier(fsys, baseDir, current.FileMap(now))
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range current {
+		info, ok := v.meta[entry.URI]
+		if !ok {
+			info = entry.TokenInfo
+		}
+		if info.Type == "" {
+			info.Type = entry.Type
+		}
+		if info.Version == "" {
+			info.Version = entry.Version
+		}
+		if info.Hash == "" {
+			info.Hash = entry.Hash
+		}
+		v.meta[entry.URI] = info
+	}
+	return v, nil
+}
+
+// NewStaticVerifierFromTokens wraps payloads already held in memory, such as
+// tokens submitted inline and checked with VerifyJWS.
+func NewStaticVerifierFromTokens(tokens map[string]json.RawMessage) *StaticVerifier {
+	resolved := make(map[string]json.RawMessage, len(tokens))
+	meta := make(map[string]TokenInfo, len(tokens))
+	for uri, payload := range tokens {
+		resolved[uri] = append([]byte(nil), payload...)
+		var info TokenInfo
+		if err := json.Unmarshal(payload, &info); err == nil {
+			info.URI = uri
+			if info.Type == "" {
+				info.Type = detectType(uri)
+			}
+			meta[uri] = info
+		}
+	}
+	return &StaticVerifier{tokens: resolved, meta: meta}
+}
+
 // VerifyRRMT ensures an RRMT token exists and carries the expected type discriminator.
 func (v *StaticVerifier) VerifyRRMT(ctx context.Context, uri string) error {
 	return v.verifyType(ctx, uri, "RRMT")
 }
 
-// VerifyCORT ensures a CORT token exists and carries the expected type discriminator.
+// VerifyRMT ensures an RMT token exists and carries the expected type discriminator.
+func (v *StaticVerifier) VerifyRMT(ctx context.Context, uri string) error {
+	return v.verifyType(ctx, uri, "RMT")
+}
+
+// VerifyIMT ensures an IMT token exists, carries the expected type
+// discriminator, names a well-formed corridor and references both source RMTs.
+func (v *StaticVerifier) VerifyIMT(ctx context.Context, uri string) error {
+	if err := v.verifyType(ctx, uri, "IMT"); err != nil {
+		return err
+	}
+	var imt struct {
+		Corridor   string `json:"corridor"`
+		References struct {
+			RMTA string `json:"rmt_a"`
+			RMTB string `json:"rmt_b"`
+		} `json:"references"`
+	}
+	if err := json.Unmarshal(v.tokens[uri], &imt); err != nil {
+		return fmt.Errorf("decode %s payload: %w", uri, err)
+	}
+	if _, ok := NormalizeCorridor(imt.Corridor); !ok {
+		return fmt.Errorf("token %s has malformed corridor %q", uri, imt.Corridor)
+	}
+	if strings.TrimSpace(imt.References.RMTA) == "" || strings.TrimSpace(imt.References.RMTB) == "" {
+		return fmt.Errorf("token %s must reference rmt_a and rmt_b", uri)
+	}
+	return nil
+}
+
+// VerifyCORT ensures a CORT token exists, carries the expected type
+// discriminator and has valid commercial terms (see ValidateCORT). Term
+// violations are returned as a *CORTError.
 func (v *StaticVerifier) VerifyCORT(ctx context.Context, uri string) error {
-	return v.verifyType(ctx, uri, "CORT")
+	if err := v.verifyType(ctx, uri, "CORT"); err != nil {
+		return err
+	}
+	if violations := ValidateCORT(v.tokens[uri]); len(violations) > 0 {
+		return &CORTError{URI: uri, Violations: violations}
+	}
+	return nil
 }
 
 // VerifyPSRT ensures a PSRT token exists and carries the expected type discriminator.
@@ -89,6 +182,16 @@ func (v *StaticVerifier) VerifyPSRT(ctx context.Context, uri string) error {
 	return v.verifyType(ctx, uri, "PSRT")
 }
 
+// VerifyAMLS ensures an AML screening token exists and carries the expected type discriminator.
+func (v *StaticVerifier) VerifyAMLS(ctx context.Context, uri string) error {
+	return v.verifyType(ctx, uri, "AMLS")
+}
+
+// VerifyAMLV ensures an AML verification token exists and carries the expected type discriminator.
+func (v *StaticVerifier) VerifyAMLV(ctx context.Context, uri string) error {
+	return v.verifyType(ctx, uri, "AMLV")
+}
+
 // Token returns the raw JSON payload for the given token URI.
 func (v *StaticVerifier) Token(uri string) (json.RawMessage, bool) {
 	data, ok := v.tokens[uri]
@@ -104,6 +207,67 @@ func (v *StaticVerifier) Metadata(uri string) (TokenInfo, bool) {
 	return info, ok
 }
 
+// NormalizeCorridor parses a corridor of the form `jur "-" jur` (section 9.4),
+// also accepting the legacy "->" separator, and returns it upper-cased with a
+// "-" separator.
+func NormalizeCorridor(corridor string) (string, bool) {
+	corridor = strings.ReplaceAll(strings.TrimSpace(corridor), "->", "-")
+	src, dst, ok := strings.Cut(strings.ToUpper(corridor), "-")
+	if !ok || !validJurisdiction(src) || !validJurisdiction(dst) {
+		return "", false
+	}
+	return src + "-" + dst, true
+}
+
+// NormalizeJurisdiction validates a two-letter jurisdiction code (section
+// 9.4) and returns it upper-cased.
+func NormalizeJurisdiction(jur string) (string, bool) {
+	jur = strings.ToUpper(strings.TrimSpace(jur))
+	return jur, validJurisdiction(jur)
+}
+
+// StringOrList decodes a claim holding a single string or a list of strings,
+// such as an RMT `jurisdiction`. An empty string yields nil.
+func StringOrList(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		if single == "" {
+			return nil
+		}
+		return []string{single}
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+	return nil
+}
+
+// ContainsFold reports whether values holds want, ignoring case.
+func ContainsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func validJurisdiction(jur string) bool {
+	if len(jur) != 2 {
+		return false
+	}
+	for _, c := range jur {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 func detectType(uri string) string {
 	switch {
 	case strings.Contains(uri, ":RRMT:"):
@@ -116,6 +280,10 @@ func detectType(uri string) string {
 		return "IMT"
 	case strings.Contains(uri, ":RMT:"):
 		return "RMT"
+	case strings.Contains(uri, ":AMLS:"):
+		return "AMLS"
+	case strings.Contains(uri, ":AMLV:"):
+		return "AMLV"
 	default:
 		return ""
 	}
@@ -9,10 +9,12 @@ import (
 
 func TestStaticVerifierHappyPath(t *testing.T) {
 	fsys := fstest.MapFS{
-		"rrmt-eu-psd3-2025.json":       {Data: []byte(`{"type":"RRMT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
-		"imt-eu-sg-2025.json":          {Data: []byte(`{"type":"IMT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
-		"cort-vodafone-visa-2025.json": {Data: []byte(`{"type":"CORT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
-		"psrt-visa-acq-123.json":       {Data: []byte(`{"type":"PSRT","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"rrmt-eu-psd3-2025.json":       {Data: []byte(`{"type":"RRMT",` + testRRMTClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"rmt-eu-psd3-2025.json":        {Data: []byte(`{"type":"RMT",` + testRMTClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"rmt-sg-psd3-2025.json":        {Data: []byte(`{"type":"RMT",` + testRMTClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"imt-eu-sg-2025.json":          {Data: []byte(`{"type":"IMT",` + testIMTClaims + `,"corridor":"EU-SG","references":{"rmt_a":"urn:a","rmt_b":"urn:b"},"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"cort-vodafone-visa-2025.json": {Data: []byte(`{"type":"CORT",` + testCORTTerms + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
+		"psrt-visa-acq-123.json":       {Data: []byte(`{"type":"PSRT",` + testPSRTClaims + `,"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z","revoked":false}`)},
 	}
 	verifier, err := NewStaticVerifier(fsys, ".", nil)
 	if err != nil {
@@ -26,6 +28,14 @@ func TestStaticVerifierHappyPath(t *testing.T) {
 			if err := verifier.VerifyRRMT(ctx, uri); err != nil {
 				t.Fatalf("VerifyRRMT %s: %v", uri, err)
 			}
+		case "RMT":
+			if err := verifier.VerifyRMT(ctx, uri); err != nil {
+				t.Fatalf("VerifyRMT %s: %v", uri, err)
+			}
+		case "IMT":
+			if err := verifier.VerifyIMT(ctx, uri); err != nil {
+				t.Fatalf("VerifyIMT %s: %v", uri, err)
+			}
 		case "CORT":
 			if err := verifier.VerifyCORT(ctx, uri); err != nil {
 				t.Fatalf("VerifyCORT %s: %v", uri, err)
@@ -34,8 +44,6 @@ func TestStaticVerifierHappyPath(t *testing.T) {
 			if err := verifier.VerifyPSRT(ctx, uri); err != nil {
 				t.Fatalf("VerifyPSRT %s: %v", uri, err)
 			}
-		case "RMT", "IMT":
-			// no signature verification required, but metadata must exist
 		default:
 			t.Fatalf("unexpected token type for %s", uri)
 		}
@@ -50,7 +58,7 @@ func TestStaticVerifierHappyPath(t *testing.T) {
 
 func TestStaticVerifierUnknownToken(t *testing.T) {
 	fsys := fstest.MapFS{
-		"rrmt-eu-psd3-2025.json": {Data: []byte(`{"type":"RRMT"}`)},
+		"rrmt-eu-psd3-2025.json": {Data: []byte(`{"type":"RRMT",` + testRRMTClaims + `}`)},
 	}
 	fileMap := FileMap{
 		"urn:lane2:token:RMT:EU:PSD3:3.2": "rrmt-eu-psd3-2025.json",
@@ -67,8 +75,10 @@ func TestStaticVerifierUnknownToken(t *testing.T) {
 
 func expectedType(uri string) string {
 	switch {
-	case strings.Contains(uri, ":RRMT:") || strings.Contains(uri, ":RMT:"):
+	case strings.Contains(uri, ":RRMT:"):
 		return "RRMT"
+	case strings.Contains(uri, ":RMT:"):
+		return "RMT"
 	case strings.Contains(uri, ":CORT:"):
 		return "CORT"
 	case strings.Contains(uri, ":PSRT:"):
@@ -95,7 +105,7 @@ func TestNewStaticVerifierInvalidJSON(t *testing.T) {
 
 func TestNewStaticVerifierTypeFallback(t *testing.T) {
 	fsys := fstest.MapFS{
-		"imt.json": {Data: []byte(`{"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z"}`)},
+		"imt.json": {Data: []byte(`{` + testIMTClaims + `,"corridor":"EU-SG","nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z"}`)},
 	}
 	fileMap := FileMap{
 		"urn:lane2:token:IMT:EU:SG:2025": "imt.json",
@@ -115,7 +125,7 @@ func TestNewStaticVerifierTypeFallback(t *testing.T) {
 
 func TestVerifyRRMTTypeMismatch(t *testing.T) {
 	fsys := fstest.MapFS{
-		"rrmt.json": {Data: []byte(`{"type":"CORT"}`)},
+		"rrmt.json": {Data: []byte(`{"type":"CORT",` + testCORTTerms + `}`)},
 	}
 	fileMap := FileMap{
 		"urn:lane2:token:RMT:EU:PSD3:3.2": "rrmt.json",
@@ -137,6 +147,8 @@ func TestDetectTypeTable(t *testing.T) {
 		"urn:lane2:token:PSRT:BAR":         "PSRT",
 		"urn:lane2:token:IMT:EU:SG:2025":   "IMT",
 		"urn:lane2:token:RMT:EU:PSD3:3.2":  "RMT",
+		"urn:lane2:token:AMLS:ACME:42":     "AMLS",
+		"urn:lane2:token:AMLV:ACME:42":     "AMLV",
 		"urn:lane2:token:UNKNOWN":          "",
 	}
 	for uri, want := range cases {
@@ -145,3 +157,95 @@ func TestDetectTypeTable(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyIMTStructure(t *testing.T) {
+	const window = `"nbf":"2000-01-01T00:00:00Z","exp":"2100-01-01T00:00:00Z"`
+	cases := map[string]struct {
+		payload string
+		want    string
+	}{
+		"valid":        {`{"type":"IMT",` + testIMTClaims + `,"corridor":"EU-SG","references":{"rmt_a":"urn:a","rmt_b":"urn:b"},` + window + `}`, ""},
+		"legacyArrow":  {`{"type":"IMT",` + testIMTClaims + `,"corridor":"EU->SG","references":{"rmt_a":"urn:a","rmt_b":"urn:b"}}`, ""},
+		"wrongType":    {`{"type":"RMT",` + testRMTClaims + `,"corridor":"EU-SG","references":{"rmt_a":"urn:a","rmt_b":"urn:b"}}`, "unexpected type"},
+		"badCorridor":  {`{"type":"IMT",` + testIMTClaims + `,"corridor":"EUR-SG","references":{"rmt_a":"urn:a","rmt_b":"urn:b"}}`, "malformed corridor"},
+		"noCorridor":   {`{"type":"IMT",` + testIMTClaims + `,"corridor":"","references":{"rmt_a":"urn:a","rmt_b":"urn:b"}}`, "malformed corridor"},
+		"missingRMTB":  {`{"type":"IMT",` + testIMTClaims + `,"corridor":"EU-SG","references":{"rmt_a":"urn:a"}}`, "rmt_a and rmt_b"},
+		"noReferences": {`{"type":"IMT",` + testIMTClaims + `,"corridor":"EU-SG"}`, "rmt_a and rmt_b"},
+	}
+	for name, tc := range cases {
+		fsys := fstest.MapFS{"imt.json": {Data: []byte(tc.payload)}}
+		verifier, err := NewStaticVerifier(fsys, ".", FileMap{"urn:imt": "imt.json"})
+		if err != nil {
+			t.Fatalf("%s: NewStaticVerifier: %v", name, err)
+		}
+		err = verifier.VerifyIMT(context.Background(), "urn:imt")
+		if tc.want == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestVerifyRMTRejectsRRMT(t *testing.T) {
+	fsys := fstest.MapFS{"rrmt.json": {Data: []byte(`{"type":"RRMT",` + testRRMTClaims + `}`)}}
+	verifier, err := NewStaticVerifier(fsys, ".", FileMap{"urn:lane2:token:RMT:EU:PSD3:3.2": "rrmt.json"})
+	if err != nil {
+		t.Fatalf("NewStaticVerifier: %v", err)
+	}
+	if err := verifier.VerifyRMT(context.Background(), "urn:lane2:token:RMT:EU:PSD3:3.2"); err == nil || !strings.Contains(err.Error(), "unexpected type") {
+		t.Fatalf("expected RRMT payload to be rejected as RMT, got %v", err)
+	}
+}
+
+func TestVerifyAMLTokens(t *testing.T) {
+	fsys := fstest.MapFS{
+		"amls.json": {Data: []byte(`{"type":"AMLS",` + testAMLSClaims + `}`)},
+		"amlv.json": {Data: []byte(`{"type":"AMLV",` + testAMLVClaims + `}`)},
+	}
+	verifier, err := NewStaticVerifier(fsys, ".", FileMap{
+		"urn:lane2:token:AMLS:ACME:42": "amls.json",
+		"urn:lane2:token:AMLV:ACME:42": "amlv.json",
+	})
+	if err != nil {
+		t.Fatalf("NewStaticVerifier: %v", err)
+	}
+	ctx := context.Background()
+	if err := verifier.VerifyAMLS(ctx, "urn:lane2:token:AMLS:ACME:42"); err != nil {
+		t.Fatalf("VerifyAMLS: %v", err)
+	}
+	if err := verifier.VerifyAMLV(ctx, "urn:lane2:token:AMLV:ACME:42"); err != nil {
+		t.Fatalf("VerifyAMLV: %v", err)
+	}
+	if err := verifier.VerifyAMLV(ctx, "urn:lane2:token:AMLS:ACME:42"); err == nil {
+		t.Fatalf("expected AMLS payload to be rejected as AMLV")
+	}
+}
+
+func TestNormalizeCorridorAndJurisdiction(t *testing.T) {
+	corridors := map[string]string{"eu-sg": "EU-SG", "EU->SG": "EU-SG", " SG-MY ": "SG-MY", "EUR-SG": "", "EU": "", "E1-SG": ""}
+	for in, want := range corridors {
+		if got, ok := NormalizeCorridor(in); got != want || ok != (want != "") {
+			t.Fatalf("NormalizeCorridor(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	jurisdictions := map[string]bool{"eu": true, "SG": true, "EUR": false, "e1": false, "": false}
+	for in, want := range jurisdictions {
+		if got, ok := NormalizeJurisdiction(in); ok != want || (ok && got != strings.ToUpper(in)) {
+			t.Fatalf("NormalizeJurisdiction(%q) = %q, %v", in, got, ok)
+		}
+	}
+	if got := StringOrList([]byte(`"EU"`)); len(got) != 1 || got[0] != "EU" {
+		t.Fatalf("unexpected single value %v", got)
+	}
+	if got := StringOrList([]byte(`["EU","SG"]`)); len(got) != 2 || !ContainsFold(got, "sg") {
+		t.Fatalf("unexpected list %v", got)
+	}
+	if StringOrList([]byte(`""`)) != nil || StringOrList(nil) != nil || StringOrList([]byte(`1`)) != nil {
+		t.Fatalf("expected empty and malformed claims to yield nil")
+	}
+}